@@ -15,6 +15,15 @@ func main() {
 	initial.InitJWTSecretKey()
 	zap.S().Infof("jwt secret key initialized")
 
+	initial.InitItemCatalogue()
+	zap.S().Infof("item catalogue initialized")
+
+	initial.InitObstacleTypes()
+	zap.S().Infof("obstacle types initialized")
+
+	initial.InitMaps()
+	zap.S().Infof("maps initialized")
+
 	initial.InitHubManager()
 	zap.S().Infof("game hubs initialized")
 
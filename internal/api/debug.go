@@ -0,0 +1,59 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"pickup/internal/game"
+)
+
+// GetRoundSeed returns the seed the current round of a room was generated from.
+func GetRoundSeed(c *gin.Context) {
+	hub := debugHub(c)
+	if hub == nil {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"roomId": hub.ID,
+		"seed":   hub.RoundSeed(),
+	})
+}
+
+// SetNextRoundSeed makes the next round of a room regenerate the map of the
+// given seed, to reproduce the obstacles, items and spawns of an earlier round.
+func SetNextRoundSeed(c *gin.Context) {
+	hub := debugHub(c)
+	if hub == nil {
+		return
+	}
+	seed, err := strconv.ParseInt(c.Query("seed"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "seed must be an integer"})
+		return
+	}
+
+	hub.SetNextSeed(seed)
+	zap.S().Infof("hub: %v next round will use seed %d", hub.ID, seed)
+	c.JSON(http.StatusOK, gin.H{
+		"roomId":         hub.ID,
+		"seed":           seed,
+		"nextRoundStart": hub.GetNextRoundStartTime().UnixMilli(),
+	})
+}
+
+func debugHub(c *gin.Context) *game.Hub {
+	roomId := c.Query("roomId")
+	if roomId == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Room ID is required"})
+		return nil
+	}
+	hub := game.Hm.GetHubById(roomId)
+	if hub == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+		return nil
+	}
+	return hub
+}
@@ -68,11 +68,11 @@ func WebsocketEndpoint(c *gin.Context) {
 	client := game.NewClient(claims.UserID, hub, conn)
 	success := hub.RegisterClient(client)
 
-	if success && !global.Dv.GetBool("RUNNING_GAME_JOIN_PROTECT") {
+	if success && !client.Spectating && !global.Dv.GetBool("RUNNING_GAME_JOIN_PROTECT") {
 		hub.InitStartPosition(client)
 		zap.S().Infof("client %s force join the running game, position init success", client.ID)
 	} else if !success {
-		hub.ClientManager.UpdateClientConnStateById(client.ID, true)
+		hub.SetClientConnected(client.ID, true)
 		if err := client.Hub.RecoverStartPosition(client); err != nil {
 			zap.S().Error("failed to recover start position", zap.Error(err))
 		}
@@ -89,9 +89,10 @@ func WebsocketEndpoint(c *gin.Context) {
 	}
 
 	hub.SendAllGameRoundStateToClient(client)
+	hub.SendRoundResultToClient(client)
 	serveWs(client)
 
-	hub.ClientManager.UpdateClientConnStateById(client.ID, false)
+	hub.SetClientConnected(client.ID, false)
 }
 
 func serveWs(client *game.Client) {
@@ -137,7 +138,16 @@ func GetGamePage(c *gin.Context) {
 }
 
 func GetGameRoom(c *gin.Context) {
-	c.HTML(http.StatusOK, "room.html", nil)
+	rooms := make([]gin.H, 0)
+	for _, hub := range game.Hm.GetHubs() {
+		rooms = append(rooms, gin.H{
+			"ID":     hub.ID,
+			"Mode":   hub.Mode.Name(),
+			"Width":  hub.Config.Width,
+			"Height": hub.Config.Height,
+		})
+	}
+	c.HTML(http.StatusOK, "room.html", gin.H{"Rooms": rooms})
 }
 
 func GetRoomStatus(c *gin.Context) {
@@ -157,8 +167,13 @@ func GetRoomStatus(c *gin.Context) {
 	state := hub.CurrentRound.State
 	hub.CurrentRound.Mu.RUnlock()
 
+	// players can join once the round is playing, until the next round starts
+	joinWindow := hub.Schedule.PhaseDuration("playing") + hub.Schedule.PhaseDuration("ended")
+
 	c.JSON(http.StatusOK, gin.H{
 		"state":          state,
 		"nextRoundStart": hub.GetNextRoundStartTime().UnixMilli(),
+		"joinWindow":     int(joinWindow.Seconds()),
+		"playersNeeded":  hub.PlayersNeeded(),
 	})
 }
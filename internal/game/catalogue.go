@@ -0,0 +1,199 @@
+package game
+
+import (
+	"fmt"
+	"pickup/internal/global"
+	"pickup/pkg/models"
+	"time"
+)
+
+// item kinds: collected items add their value to the score, effect items
+// trigger the effect they name, hazards go off on the player stepping on them
+const (
+	ItemKindCollect = "collect"
+	ItemKindEffect  = "effect"
+	ItemKindHazard  = "hazard"
+)
+
+// ItemDefinition is one entry of the ITEMS catalogue in config.yaml.
+type ItemDefinition struct {
+	Type        string `mapstructure:"TYPE"`
+	Value       int    `mapstructure:"VALUE"`
+	Count       int    `mapstructure:"COUNT"`        // placed when a round is prepared
+	Weight      int    `mapstructure:"WEIGHT"`       // share of the mid-round spawns
+	LifetimeSec int    `mapstructure:"LIFETIME_SEC"` // 0 keeps the item until collected
+	Kind        string `mapstructure:"KIND"`
+	Effect      string `mapstructure:"EFFECT"`
+	DurationSec int    `mapstructure:"DURATION_SEC"` // how long a timed effect lasts
+}
+
+// defaultEffectDuration is used by effect items without DURATION_SEC.
+const defaultEffectDuration = 5 * time.Second
+
+func (d *ItemDefinition) duration() time.Duration {
+	if d.DurationSec <= 0 {
+		return defaultEffectDuration
+	}
+	return time.Duration(d.DurationSec) * time.Second
+}
+
+// itemEffect applies the effect of a collected effect item to a player.
+type itemEffect func(h *Hub, userId string, def *ItemDefinition) error
+
+var itemEffects = map[string]itemEffect{}
+
+type ItemCatalogue struct {
+	items  []*ItemDefinition
+	byType map[string]*ItemDefinition
+}
+
+var Catalogue *ItemCatalogue
+
+// LoadItemCatalogue reads and validates the ITEMS catalogue.
+func LoadItemCatalogue() (*ItemCatalogue, error) {
+	var items []*ItemDefinition
+	if err := global.Dv.UnmarshalKey("ITEMS", &items); err != nil {
+		return nil, fmt.Errorf("failed to read item catalogue: %w", err)
+	}
+
+	catalogue := &ItemCatalogue{
+		items:  items,
+		byType: make(map[string]*ItemDefinition, len(items)),
+	}
+	for _, def := range items {
+		if def.Type == "" {
+			return nil, fmt.Errorf("item without TYPE in catalogue")
+		}
+		if _, exists := catalogue.byType[def.Type]; exists {
+			return nil, fmt.Errorf("item %s is defined twice", def.Type)
+		}
+		if def.Kind == "" {
+			def.Kind = ItemKindCollect
+		}
+		switch def.Kind {
+		case ItemKindCollect:
+		case ItemKindEffect:
+			if _, ok := itemEffects[def.Effect]; !ok {
+				return nil, fmt.Errorf("item %s has unknown effect: %q", def.Type, def.Effect)
+			}
+		case ItemKindHazard:
+			if _, ok := itemEffects[def.Effect]; def.Effect != "" && !ok {
+				return nil, fmt.Errorf("item %s has unknown effect: %q", def.Type, def.Effect)
+			}
+		default:
+			return nil, fmt.Errorf("item %s has unknown kind: %s", def.Type, def.Kind)
+		}
+		catalogue.byType[def.Type] = def
+	}
+	return catalogue, nil
+}
+
+func (c *ItemCatalogue) Items() []*ItemDefinition {
+	return c.items
+}
+
+func (c *ItemCatalogue) Get(itemType string) (*ItemDefinition, bool) {
+	def, ok := c.byType[itemType]
+	return def, ok
+}
+
+// ObstacleDefinition is one entry of OBSTACLE_TYPES in config.yaml.
+type ObstacleDefinition struct {
+	Type       string  `mapstructure:"TYPE"`
+	HP         int     `mapstructure:"HP"`          // attacks needed to break it, 0 can't be broken
+	Weight     int     `mapstructure:"WEIGHT"`      // share of the generated obstacles
+	DropChance float64 `mapstructure:"DROP_CHANCE"` // chance of leaving an item when broken
+}
+
+// ObstacleTypes lists the obstacle types, the first one is used by maps.
+var ObstacleTypes = []*ObstacleDefinition{{Type: "rock", Weight: 1}}
+
+// LoadObstacleTypes reads and validates OBSTACLE_TYPES, without it every
+// obstacle is an unbreakable rock.
+func LoadObstacleTypes() ([]*ObstacleDefinition, error) {
+	var types []*ObstacleDefinition
+	if err := global.Dv.UnmarshalKey("OBSTACLE_TYPES", &types); err != nil {
+		return nil, fmt.Errorf("failed to read obstacle types: %w", err)
+	}
+	if len(types) == 0 {
+		return ObstacleTypes, nil
+	}
+
+	seen := make(map[string]bool, len(types))
+	for _, def := range types {
+		if def.Type == "" {
+			return nil, fmt.Errorf("obstacle type without TYPE")
+		}
+		if seen[def.Type] {
+			return nil, fmt.Errorf("obstacle type %s is defined twice", def.Type)
+		}
+		seen[def.Type] = true
+		if def.HP < 0 {
+			return nil, fmt.Errorf("obstacle type %s has negative HP", def.Type)
+		}
+		if def.DropChance < 0 || def.DropChance > 1 {
+			return nil, fmt.Errorf("obstacle type %s has DROP_CHANCE outside 0..1", def.Type)
+		}
+	}
+	return types, nil
+}
+
+func obstacleType(name string) *ObstacleDefinition {
+	for _, def := range ObstacleTypes {
+		if def.Type == name {
+			return def
+		}
+	}
+	return nil
+}
+
+// pickObstacleType picks an obstacle type in proportion to its weight.
+func pickObstacleType(rng *lockedRand) *ObstacleDefinition {
+	total := 0
+	for _, def := range ObstacleTypes {
+		total += max(def.Weight, 0)
+	}
+	if total == 0 {
+		return ObstacleTypes[0]
+	}
+	pick := rng.Intn(total)
+	for _, def := range ObstacleTypes {
+		if pick < max(def.Weight, 0) {
+			return def
+		}
+		pick -= max(def.Weight, 0)
+	}
+	return ObstacleTypes[0]
+}
+
+func newObstacle(def *ObstacleDefinition, x, y int) *models.Obstacle {
+	return &models.Obstacle{
+		Position: &models.Position{X: x, Y: y},
+		Type:     def.Type,
+		HP:       def.HP,
+		MaxHP:    def.HP,
+	}
+}
+
+// newItemAction places an item of the catalogue, its lifetime starts now.
+func newItemAction(def *ItemDefinition, position *models.Position, now time.Time) *models.ItemAction {
+	itemAction := &models.ItemAction{
+		ID:       "",
+		Valid:    true,
+		Item:     &models.Item{Type: def.Type, Value: def.Value},
+		Position: position,
+	}
+	if def.LifetimeSec > 0 {
+		itemAction.ExpiresAt = now.Add(time.Duration(def.LifetimeSec) * time.Second).UnixMilli()
+	}
+	return itemAction
+}
+
+// applyItemEffect triggers the effect of a collected effect item.
+func (h *Hub) applyItemEffect(userId string, def *ItemDefinition) error {
+	effect, ok := itemEffects[def.Effect]
+	if !ok {
+		return fmt.Errorf("unsupported item effect: %s", def.Effect)
+	}
+	return effect(h, userId, def)
+}
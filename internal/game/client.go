@@ -18,7 +18,9 @@ type Client struct {
 	Send         chan *models.GameMsg
 	Done          chan struct{}
 	AllowJoinGame bool
+	Spectating    bool // sits out the round after missing the lobby ready-check
 	mu            sync.Mutex
+	dropOnce      sync.Once
 }
 
 func NewClient(id string, hub *Hub, conn *websocket.Conn) *Client {
@@ -64,12 +66,26 @@ func (c *Client) ReadPump(ctx context.Context) error {
 }
 
 func (c *Client) handleGameMsg(gameMsg *models.GameMsg) error {
+	// the ready-check happens before the round, so it skips the round checks
+	if gameMsg.Type == models.PlayerReadyType {
+		c.Hub.SetPlayerReady(c.ID)
+		return nil
+	}
+	if gameMsg.Type == models.ChooseTeamType {
+		return c.handleChooseTeam(gameMsg)
+	}
+
+	if c.Spectating {
+		zap.S().Debugf("client %v is spectating the current round", c.ID)
+		return nil
+	}
+
 	if !c.AllowJoinGame && global.Dv.GetBool("RUNNING_GAME_JOIN_PROTECT") {
 		zap.S().Debugf("client is not active in the current round")
 		return nil
 	}
 
-	if c.Hub.CurrentRound.State != "playing" {
+	if !c.Hub.isRoundRunning() {
 		zap.S().Debug("current round is not playing")
 		return nil
 	}
@@ -79,6 +95,8 @@ func (c *Client) handleGameMsg(gameMsg *models.GameMsg) error {
 		return c.handlePlayerPosition(gameMsg)
 	case models.ItemActionType:
 		return c.handleItemAction(gameMsg)
+	case models.AttackType:
+		return c.handleAttack(gameMsg)
 	case models.PlayerChatMsgType:
 		return nil
 	default:
@@ -106,6 +124,27 @@ func (c *Client) handleItemAction(gameMsg *models.GameMsg) error {
 	return nil
 }
 
+func (c *Client) handleAttack(gameMsg *models.GameMsg) error {
+	attack, err := gameMsgContentSwapper[models.Attack](gameMsg)
+	if err != nil {
+		return err
+	}
+	attack.ID = c.ID
+	c.Hub.AttackChan <- attack
+	return nil
+}
+
+func (c *Client) handleChooseTeam(gameMsg *models.GameMsg) error {
+	choice, err := gameMsgContentSwapper[models.TeamChoice](gameMsg)
+	if err != nil {
+		return err
+	}
+	if err := c.Hub.ChooseTeam(c.ID, choice.Team); err != nil {
+		zap.S().Debugf("client %v: %v", c.ID, err)
+	}
+	return nil
+}
+
 func (c *Client) WritePump(ctx context.Context) error {
 	zap.S().Infof("WritePump start Client: %v", c.ID)
 	defer c.Conn.Close()
@@ -114,6 +153,8 @@ func (c *Client) WritePump(ctx context.Context) error {
 		select {
 		case <-ctx.Done():
 			return fmt.Errorf("WritePump for client %s stopped due to context cancellation", c.ID)
+		case <-c.Done:
+			return fmt.Errorf("client %s dropped, its send buffer is full", c.ID)
 		case msg, ok := <-c.Send:
 			if !ok {
 				return c.writeCloseMessage()
@@ -125,6 +166,15 @@ func (c *Client) WritePump(ctx context.Context) error {
 	}
 }
 
+// drop ends the connection of a client that can't keep up, it gets the full
+// state again when it reconnects.
+func (c *Client) drop() {
+	c.dropOnce.Do(func() {
+		zap.S().Warnf("send buffer of client %s is full, dropping the connection", c.ID)
+		close(c.Done)
+	})
+}
+
 func (c *Client) writeCloseMessage() error {
 	return c.Conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
 }
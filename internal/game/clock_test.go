@@ -0,0 +1,162 @@
+package game
+
+import (
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock only moves when a test advances it. Its ticks are handed over
+// one at a time, so a tick has been taken by its loop when Advance returns.
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	tickers []*fakeTicker
+}
+
+type fakeTicker struct {
+	c       chan time.Time
+	period  time.Duration
+	next    time.Time
+	stopped bool
+	clock   *fakeClock
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) NewTicker(d time.Duration) Ticker {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ticker := &fakeTicker{c: make(chan time.Time), period: d, next: c.now.Add(d), clock: c}
+	c.tickers = append(c.tickers, ticker)
+	return ticker
+}
+
+func (t *fakeTicker) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTicker) Stop() {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	t.stopped = true
+}
+
+// Advance moves the clock forward by d, delivering every tick due on the way
+// in time order.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	end := c.now.Add(d)
+	c.mu.Unlock()
+
+	for {
+		c.mu.Lock()
+		var due *fakeTicker
+		for _, ticker := range c.tickers {
+			if !ticker.stopped && !ticker.next.After(end) && (due == nil || ticker.next.Before(due.next)) {
+				due = ticker
+			}
+		}
+		if due == nil {
+			c.now = end
+			c.mu.Unlock()
+			return
+		}
+		c.now = due.next
+		due.next = due.next.Add(due.period)
+		at := c.now
+		c.mu.Unlock()
+
+		due.c <- at
+	}
+}
+
+// waitForTickers waits until n tickers were created, the loops of a hub
+// create theirs once they start.
+func (c *fakeClock) waitForTickers(t *testing.T, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		c.mu.Lock()
+		created := len(c.tickers)
+		c.mu.Unlock()
+		if created >= n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("waited for %d tickers", n)
+}
+
+func TestFakeClockDeliversTicksInOrder(t *testing.T) {
+	clock := newFakeClock()
+	start := clock.Now()
+	slow, fast := clock.NewTicker(100*time.Millisecond), clock.NewTicker(40*time.Millisecond)
+
+	got := make(chan string, 10)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case at := <-slow.C():
+				got <- "slow " + at.Sub(start).String()
+			case at := <-fast.C():
+				got <- "fast " + at.Sub(start).String()
+			case <-done:
+				return
+			}
+		}
+	}()
+	clock.Advance(100 * time.Millisecond)
+	close(done)
+
+	want := []string{"fast 40ms", "fast 80ms", "slow 100ms"}
+	for _, w := range want {
+		if g := <-got; g != w {
+			t.Fatalf("got tick %q, want %q", g, w)
+		}
+	}
+	if now := clock.Now().Sub(start); now != 100*time.Millisecond {
+		t.Fatalf("clock at %v after advancing 100ms", now)
+	}
+}
+
+func TestRoundRunsThroughItsPhasesOnTheHubClock(t *testing.T) {
+	h, clock := newTestHub(t, RoomConfig{ID: "clock", Mode: "classic"}, "a", "b")
+	go h.Run()
+	defer h.Stop()
+	// the tick loop and the round loop
+	clock.waitForTickers(t, 2)
+
+	states := []string{h.roundState()}
+	entered := map[string]time.Time{}
+	for i := 0; i < 2000 && states[len(states)-1] != "ended"; i++ {
+		clock.Advance(100 * time.Millisecond)
+		// a tick of the round loop is applied once the next one is taken
+		clock.Advance(100 * time.Millisecond)
+		if state := h.roundState(); state != states[len(states)-1] {
+			states = append(states, state)
+			entered[state] = clock.Now()
+		}
+	}
+
+	want := []string{"waiting", "cleanup", "preparing", "playing", "ended"}
+	if !slices.Equal(states, want) {
+		t.Fatalf("round went through %v, want %v", states, want)
+	}
+	// the round loop may still apply the tick a state is read after, so
+	// either end of the round can be seen a step late
+	played := entered["ended"].Sub(entered["playing"])
+	if expected := h.Schedule.PhaseDuration("playing"); played < expected-200*time.Millisecond || played > expected+time.Second {
+		t.Fatalf("round played for %v on the hub clock, want %v", played, expected)
+	}
+}
@@ -0,0 +1,119 @@
+package game
+
+import "pickup/pkg/models"
+
+var gridDirections = []models.Position{{X: 0, Y: -1}, {X: 0, Y: 1}, {X: -1, Y: 0}, {X: 1, Y: 0}}
+
+// floodFill returns the cells reachable from start with single steps, moving
+// around the blocked cells of a width x height grid.
+func floodFill(width, height int, blocked map[models.Position]bool, start models.Position) map[models.Position]bool {
+	reached := map[models.Position]bool{start: true}
+	queue := []models.Position{start}
+	for len(queue) > 0 {
+		cell := queue[0]
+		queue = queue[1:]
+		for _, direction := range gridDirections {
+			next := models.Position{X: cell.X + direction.X, Y: cell.Y + direction.Y}
+			if next.X < 0 || next.X >= width || next.Y < 0 || next.Y >= height {
+				continue
+			}
+			if blocked[next] || reached[next] {
+				continue
+			}
+			reached[next] = true
+			queue = append(queue, next)
+		}
+	}
+	return reached
+}
+
+// isConnected reports whether every free cell of the grid can be reached
+// from every other one.
+func isConnected(width, height int, blocked map[models.Position]bool) bool {
+	free := width*height - len(blocked)
+	if free <= 0 {
+		return true
+	}
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			start := models.Position{X: x, Y: y}
+			if !blocked[start] {
+				return len(floodFill(width, height, blocked, start)) == free
+			}
+		}
+	}
+	return true
+}
+
+// pathStep returns the first step of a shortest path from start to the
+// nearest target cell, moving around the blocked cells. Targets further than
+// maxSteps are out of reach, 0 doesn't limit the distance.
+func pathStep(width, height int, blocked, targets map[models.Position]bool, start models.Position, maxSteps int) (models.Position, bool) {
+	firstStep := map[models.Position]models.Position{start: start}
+	distance := map[models.Position]int{start: 0}
+	queue := []models.Position{start}
+	for len(queue) > 0 {
+		cell := queue[0]
+		queue = queue[1:]
+		if maxSteps > 0 && distance[cell] >= maxSteps {
+			continue
+		}
+		for _, direction := range gridDirections {
+			next := models.Position{X: cell.X + direction.X, Y: cell.Y + direction.Y}
+			if next.X < 0 || next.X >= width || next.Y < 0 || next.Y >= height {
+				continue
+			}
+			if _, seen := distance[next]; seen {
+				continue
+			}
+			step := firstStep[cell]
+			if cell == start {
+				step = next
+			}
+			if targets[next] {
+				return step, true
+			}
+			if blocked[next] {
+				continue
+			}
+			firstStep[next] = step
+			distance[next] = distance[cell] + 1
+			queue = append(queue, next)
+		}
+	}
+	return models.Position{}, false
+}
+
+// lineCells returns the cells a straight line from one cell to another
+// passes through, without the two ends.
+func lineCells(from, to models.Position) []models.Position {
+	cells := make([]models.Position, 0)
+	if from == to {
+		return cells
+	}
+	dx, dy := abs(to.X-from.X), -abs(to.Y-from.Y)
+	stepX, stepY := 1, 1
+	if from.X > to.X {
+		stepX = -1
+	}
+	if from.Y > to.Y {
+		stepY = -1
+	}
+	err := dx + dy
+	cell := from
+	for {
+		double := 2 * err
+		if double >= dy {
+			err += dy
+			cell.X += stepX
+		}
+		if double <= dx {
+			err += dx
+			cell.Y += stepY
+		}
+		if cell == to {
+			return cells
+		}
+		cells = append(cells, cell)
+	}
+}
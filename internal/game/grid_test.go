@@ -0,0 +1,62 @@
+package game
+
+import (
+	"testing"
+
+	"pickup/pkg/models"
+)
+
+func TestFloodFillStopsAtWalls(t *testing.T) {
+	// a wall down the middle column of a 3x3 grid
+	wall := map[models.Position]bool{{X: 1, Y: 0}: true, {X: 1, Y: 1}: true, {X: 1, Y: 2}: true}
+	reached := floodFill(3, 3, wall, models.Position{X: 0, Y: 0})
+	if len(reached) != 3 {
+		t.Fatalf("reached %d cells, want the 3 of the left column", len(reached))
+	}
+	for cell := range reached {
+		if cell.X != 0 {
+			t.Fatalf("reached %v across the wall", cell)
+		}
+	}
+	if isConnected(3, 3, wall) {
+		t.Fatal("grid split by a wall is connected")
+	}
+
+	delete(wall, models.Position{X: 1, Y: 2})
+	if !isConnected(3, 3, wall) {
+		t.Fatal("grid with a gap in the wall is not connected")
+	}
+	if !isConnected(2, 2, map[models.Position]bool{{X: 0, Y: 0}: true, {X: 0, Y: 1}: true, {X: 1, Y: 0}: true, {X: 1, Y: 1}: true}) {
+		t.Fatal("a full grid has no free cells to cut off")
+	}
+}
+
+func TestGeneratedObstaclesKeepTheGridConnected(t *testing.T) {
+	setConfig(t, "OBSNUMBER", 60)
+	h, _ := newTestHub(t, RoomConfig{ID: "grid", Mode: "classic"})
+	width, height := h.GridSize()
+
+	lastRowOrColumn := false
+	for seed := int64(1); seed <= 20; seed++ {
+		h.ClearPreviousRoundData()
+		h.rng.Seed(seed)
+		h.InitObstacles()
+
+		blocked := make(map[models.Position]bool)
+		for _, obstacle := range h.GetObstacles() {
+			blocked[*obstacle.Position] = true
+			if obstacle.X == width-1 || obstacle.Y == height-1 {
+				lastRowOrColumn = true
+			}
+		}
+		if len(blocked) == 0 {
+			t.Fatalf("seed %d placed no obstacles", seed)
+		}
+		if !isConnected(width, height, blocked) {
+			t.Fatalf("seed %d cut off part of the grid", seed)
+		}
+	}
+	if !lastRowOrColumn {
+		t.Fatal("no obstacle was placed on the last row or column")
+	}
+}
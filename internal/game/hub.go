@@ -5,23 +5,60 @@ import (
 	"go.uber.org/zap"
 	"pickup/pkg/models"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 var Hm *HubManager
 
 type Hub struct {
 	ID             string
+	Config         RoomConfig
 	ClientManager  *ClientManager
 	HubManager     *HubManager
 	OccupiedInMap  sync.Map // map[positionString]*models.Position (for occupied check)
-	ObstaclesInMap []*models.Position
+	ObstaclesInMap []*models.Obstacle
 	ItemsInMap     sync.Map // map[positionString]*models.ItemAction (for game actions)
 	UsersInMap     sync.Map // map[userIdString]*models.Position (for player move validate)
 	Scores         sync.Map // map[userIdString]int (player score storage)
+	scoredAt       sync.Map // map[userIdString]time.Time (last score change, for tie-breaks)
+	TeamScores     sync.Map // map[teamString]int (team score storage, in rooms with TEAMS)
 	PositionChan   chan *models.PlayerPosition
 	ActionChan     chan *models.ItemAction
+	AttackChan     chan *models.Attack
 	MsgChan        chan *models.ChatMsg
 	CurrentRound   *Round
+	Schedule       *RoundSchedule
+	Clock          Clock
+	Mode           GameMode
+	quit           chan struct{}             // closed by Stop
+	loops          sync.WaitGroup            // the Run and round loops
+	inputs         map[string][]*playerInput // only touched by the Run loop
+	tickCount      uint64
+	outbox         []*outboxMsg
+	outboxMu       sync.Mutex
+	nextItemSpawn  time.Time // only touched by the Run loop, zero between rounds
+	nextCurseDrain time.Time // only touched by the Run loop, zero between rounds
+	nextEntityMove time.Time // only touched by the Run loop, zero between rounds
+	stats          map[string]*playerStats
+	lastResult     *models.RoundResult
+	resultHooks    []func(result *models.RoundResult)
+	effects        map[string]map[string]*models.Action // map[userIdString]map[effect]*models.Action
+	entities       map[string]*npc                      // map[entityIdString]*npc, guards and monsters on the board
+	seeds          *lockedRand                          // hands out the round seeds
+	rng            *lockedRand                          // map generation of the current round, reseeded per round
+	playRng        *lockedRand                          // spawns, drops, entity moves and late starts while the round plays
+	gameMap        atomic.Pointer[GameMap]              // map of the current round, nil when generated
+	mapIndex       int                                  // position in the map rotation, only touched by the round
+	arena          atomic.Pointer[arenaSize]            // board size of the current round when scaled to the players
+	statsMu        sync.Mutex
+	effectsMu      sync.Mutex
+	entitiesMu     sync.Mutex
+	views          map[string]map[string]*models.ViewChange // map[userIdString]map[objectKey]*models.ViewChange, what each player saw last tick
+	viewsMu        sync.Mutex
+	teams          map[string]string // map[userIdString]teamString, teams of the current round
+	teamChoices    map[string]string // map[userIdString]teamString, picked with chooseTeam
+	teamsMu        sync.Mutex
 	mu             sync.RWMutex
 	obstaclesMu    sync.RWMutex
 }
@@ -30,11 +67,12 @@ func (h *Hub) RegisterClient(client *Client) bool {
 	if oldClient, exists := h.ClientManager.GetClientByID(client.ID); exists {
 		// sync client game state
 		client.AllowJoinGame = oldClient.AllowJoinGame
+		client.Spectating = oldClient.Spectating
 
 		// change to new client conn
 		h.ClientManager.clientsById[oldClient.ID] = client
 		h.ClientManager.clients[client] = true
-		h.ClientManager.UpdateClientConnStateById(client.ID, true)
+		h.SetClientConnected(client.ID, true)
 
 		// no register will return false and recover the previous game state for client
 		zap.S().Debug("Client exists, no need to register", zap.String("client", client.ID))
@@ -42,31 +80,60 @@ func (h *Hub) RegisterClient(client *Client) bool {
 	} else {
 		// register
 		h.ClientManager.RegisterClient(client)
+		h.recordConnection(client.ID, true, h.Clock.Now())
 		zap.S().Debug("Client not exists, need to register", zap.String("client", client.ID))
 		return true
 	}
 }
 
 func (h *Hub) SendAllGameRoundStateToClient(client *Client) {
+	client.Hub.SendRoomInfoToClient(client)
 	client.Hub.SendObstaclesToClient(client)
 	client.Hub.SendAllItemToClient(client)
+	client.Hub.SendAllEntitiesToClient(client)
 	client.Hub.SendAllPlayerPositionToClient(client)
+	client.Hub.SendTeamStateToClient(client)
 	client.Hub.SendAllScoresToClient(client)
+	client.Hub.SendAllEffectsToClient(client)
+	if syncer, ok := client.Hub.Mode.(stateSyncer); ok {
+		syncer.SyncState(client.Hub, client)
+	}
+	client.Hub.resetView(client.ID)
+}
+
+func (h *Hub) SendRoomInfoToClient(client *Client) {
+	width, height := h.GridSize()
+	client.Send <- &models.GameMsg{
+		Type: models.RoomInfoType,
+		Content: &models.RoomInfo{
+			ID:     h.ID,
+			Mode:   h.Mode.Name(),
+			Map:    h.mapName(),
+			Width:  width,
+			Height: height,
+
+			VisibilityRadius: h.Config.VisibilityRadius,
+			LineOfSight:      h.Config.LineOfSight,
+		},
+	}
 }
 
 func (h *Hub) SendAllItemToClient(client *Client) {
+	s := h.sightOf(client.ID)
 	h.ItemsInMap.Range(func(key, value interface{}) bool {
 		msg := &models.GameMsg{
 			Type:    "itemPosition",
 			Content: value.(*models.ItemAction),
 		}
-		client.Send <- msg
+		if h.visibleTo(client.ID, s, msg) {
+			client.Send <- msg
+		}
 		return true
 	})
 }
 
 func (h *Hub) SendObstaclesToClient(client *Client) {
-	for _, obstacle := range h.ObstaclesInMap {
+	for _, obstacle := range h.GetObstacles() {
 		msg := &models.GameMsg{
 			Type:    "obstaclePosition",
 			Content: obstacle,
@@ -78,6 +145,7 @@ func (h *Hub) SendObstaclesToClient(client *Client) {
 func (h *Hub) SendAllPlayerPositionToClient(client *Client) {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
+	s := h.sightOf(client.ID)
 	h.UsersInMap.Range(func(key, value interface{}) bool {
 		userId, ok := key.(string)
 		if !ok {
@@ -107,7 +175,9 @@ func (h *Hub) SendAllPlayerPositionToClient(client *Client) {
 			Content: playPosition,
 		}
 
-		client.Send <- msg
+		if h.visibleTo(client.ID, s, msg) {
+			client.Send <- msg
+		}
 
 		return true
 	})
@@ -123,13 +193,8 @@ func (h *Hub) GetClientManager() *ClientManager {
 func (h *Hub) CleanupClientGameState(client *Client) {
 	userId := client.ID
 
-	position, err := h.GetPlayerPositionByUserId(userId)
-	if err != nil {
-		zap.S().Errorf("failed to get the current position for user %s, due to:%v", userId, err.Error())
-	}
 	// clean hub state
-	h.OccupiedInMap.Delete(fmt.Sprintf("%d-%d", position.X, position.Y))
-	h.UsersInMap.Delete(userId)
+	h.removePlayerFromMap(userId)
 	h.Scores.Delete(userId)
 
 	// clean clientManager state
@@ -140,43 +205,62 @@ func (h *Hub) CleanupClientGameState(client *Client) {
 func (h *Hub) Run() {
 	zap.S().Infof("Hub %s is running", h.ID)
 
-	go h.ManageGameRounds()
+	h.loops.Add(2)
+	defer h.loops.Done()
+	go func() {
+		defer h.loops.Done()
+		h.ManageGameRounds()
+	}()
+
+	ticker := h.Clock.NewTicker(h.tickInterval())
+	defer ticker.Stop()
 
 	for {
 		select {
+		case <-h.quit:
+			return
 		case playerPosition := <-h.PositionChan:
-			err := h.handlePositionUpdate(playerPosition)
-			if err != nil {
-				zap.S().Errorf("failed handling PlayerPotition due to: %s", err.Error())
-			}
+			h.queueInput(playerPosition.ID, &playerInput{position: playerPosition})
 		case itemAction := <-h.ActionChan:
-			err := h.handleItemAction(itemAction)
-			if err != nil {
-				zap.S().Errorf("failed handling ItemAction due to: %s", err.Error())
-			}
+			h.queueInput(itemAction.ID, &playerInput{action: itemAction})
+		case attack := <-h.AttackChan:
+			h.queueInput(attack.ID, &playerInput{attack: attack})
+		case <-ticker.C():
+			h.step()
 		}
 	}
 }
 
+// Stop ends the loops started by Run and waits for them to return.
+func (h *Hub) Stop() {
+	close(h.quit)
+	h.loops.Wait()
+}
+
 func (h *Hub) broadcastSingleScore(userId string, score int) {
 	msg := &models.GameMsg{
-		Type: "score",
-		Content: &models.ScoreUpdate{
-			ID:    userId,
-			Score: score,
-		},
+		Type:    "score",
+		Content: h.scoreUpdate(userId, score),
+	}
+	h.broadcast(msg)
+}
+
+// awardScore gives the same points to every player, for modes scoring the
+// time spent somewhere rather than items collected.
+func (h *Hub) awardScore(userIds []string, points int) {
+	if points == 0 {
+		return
+	}
+	for _, userId := range userIds {
+		h.broadcastSingleScore(userId, h.updateScore(userId, points))
 	}
-	h.ClientManager.BroadcastAll(msg)
 }
 
 func (h *Hub) SendAllScoresToClient(client *Client) {
 	h.Scores.Range(func(userId, score interface{}) bool {
 		msg := &models.GameMsg{
-			Type: "score",
-			Content: &models.ScoreUpdate{
-				ID:    userId.(string),
-				Score: score.(int),
-			},
+			Type:    "score",
+			Content: h.scoreUpdate(userId.(string), score.(int)),
 		}
 		client.Send <- msg
 		return true
@@ -191,7 +275,7 @@ func (h *Hub) sendErrorToClient(userId string, errorMsg string) {
 			Error: errorMsg,
 		},
 	}
-	h.ClientManager.SendToClient(userId, msg)
+	h.sendToClient(userId, msg)
 }
 
 func (h *Hub) sendAlertToUser(userId string, alertMsg string) {
@@ -202,7 +286,7 @@ func (h *Hub) sendAlertToUser(userId string, alertMsg string) {
 			Text: alertMsg,
 		},
 	}
-	h.ClientManager.SendToClient(userId, msg)
+	h.sendToClient(userId, msg)
 }
 
 func (h *Hub) broadcastCollectedItem(itemAction *models.ItemAction) {
@@ -211,25 +295,14 @@ func (h *Hub) broadcastCollectedItem(itemAction *models.ItemAction) {
 		Type:    models.ItemCollectedType,
 		Content: itemAction,
 	}
-	h.ClientManager.BroadcastAll(msg)
+	h.broadcast(msg)
 }
 
 func (h *Hub) handleItemAction(itemAction *models.ItemAction) error {
-	positionString := fmt.Sprintf("%d-%d", itemAction.Position.X, itemAction.Position.Y)
-	itemInMap, err := h.getItemInMap(positionString)
-	if err != nil {
-		return fmt.Errorf("failed to get item in map: %w", err)
-	}
-
-	switch itemInMap.Item.Type {
-	case "coin", "diamond":
-		h.ItemsInMap.Delete(positionString)
-		newScore := h.updateScore(itemAction.ID, itemInMap.Item.Value)
-		h.broadcastCollectedItem(itemInMap)
-		h.broadcastSingleScore(itemAction.ID, newScore)
-	default:
-		return fmt.Errorf("unknown item type: %s", itemInMap.Item.Type)
+	if err := h.Mode.HandleAction(h, itemAction); err != nil {
+		return err
 	}
+	h.checkRoundEnd()
 	return nil
 }
 
@@ -245,35 +318,74 @@ func (h *Hub) handlePositionUpdate(position *models.PlayerPosition) error {
 	if !ok {
 		return fmt.Errorf("no current position found for user %s", userId)
 	}
+	// standing still (start and recovered positions) is not a move
+	isMove := *currentPosition.(*models.Position) != *newPosition
+
+	if effect := h.immobilizedBy(userId); isMove && effect != "" {
+		h.recordMove(userId, false)
+		h.sendInvalidPositionToClient("You are "+effect, userId)
+		return fmt.Errorf("user %s is %s", userId, effect)
+	}
+
 	// check move
-	if reason, ok := IsValidMove(currentPosition.(*models.Position), newPosition); !ok {
+	maxSteps := 1
+	if h.hasEffect(userId, EffectSpeed) {
+		maxSteps = 2
+	}
+	width, height := h.GridSize()
+	if reason, ok := IsValidMove(currentPosition.(*models.Position), newPosition, width, height, maxSteps); !ok {
+		h.recordMove(userId, false)
 		h.sendInvalidPositionToClient(reason, userId)
 		return fmt.Errorf("invalid move from user %s", userId)
 	}
 
-	// check occupied
+	phasing := h.hasEffect(userId, EffectPhase)
+	if !phasing && h.isPathBlocked(currentPosition.(*models.Position), newPosition) {
+		h.recordMove(userId, false)
+		h.sendInvalidPositionToClient("The way is blocked", userId)
+		return fmt.Errorf("blocked move from user %s", userId)
+	}
+
+	// check occupied, phasing players walk into obstacles but not into
+	// players, teammates may share a cell
 	newPositionString := fmt.Sprintf("%d-%d", newPosition.X, newPosition.Y)
 	occupiedPosition, ok := h.OccupiedInMap.Load(newPositionString)
-	if ok {
+	targetId, onPlayer := h.playerAt(newPosition.X, newPosition.Y)
+	onObstacle := h.isObstacleAt(newPosition.X, newPosition.Y)
+	passing := onPlayer && targetId != userId && h.passesThrough(userId, targetId) && (!onObstacle || phasing)
+	if ok && !passing && !(phasing && onObstacle && !onPlayer) {
+		if isMove && onPlayer {
+			h.passCurse(userId, targetId)
+			h.tagPlayer(userId, targetId)
+		}
+		if entityId, onEntity := h.entityAt(newPosition.X, newPosition.Y); isMove && onEntity {
+			h.catchPlayer(userId, entityId)
+		}
 		errMsg := fmt.Sprintf("%v occupied position %v\n", newPositionString, occupiedPosition.(*models.Position))
 		zap.S().Debug(errMsg)
 		h.sendErrorToClient(userId, errMsg)
+		if isMove {
+			h.recordMove(userId, false)
+		}
 		// still need to send server position to sync front-end position
 		h.sendInvalidPositionToClient(errMsg, userId)
 		return fmt.Errorf(errMsg)
 	}
 
 	// remove previous position
-	currentPositionString := fmt.Sprintf("%d-%d", currentPosition.(*models.Position).X, currentPosition.(*models.Position).Y)
-	h.OccupiedInMap.Delete(currentPositionString)
 	h.UsersInMap.Delete(userId)
+	h.releaseCell(currentPosition.(*models.Position))
 
 	// save new position
 	h.UsersInMap.Store(userId, newPosition)
 	h.OccupiedInMap.Store(newPositionString, newPosition)
 
 	// final
+	if isMove {
+		h.recordMove(userId, true)
+	}
 	h.broadcastValidPositionToAllClients(position)
+	h.Mode.OnPlayerMoved(h, userId, newPosition)
 	return nil
 }
 
@@ -293,7 +405,7 @@ func (h *Hub) sendInvalidPositionToClient(reason string, userId string) {
 	}
 
 	// invalid position only sent to client itself
-	h.ClientManager.SendToClient(userId, msg)
+	h.sendToClient(userId, msg)
 }
 
 func (h *Hub) broadcastValidPositionToAllClients(position *models.PlayerPosition) {
@@ -302,5 +414,5 @@ func (h *Hub) broadcastValidPositionToAllClients(position *models.PlayerPosition
 		Type:    models.PlayerPositionType,
 		Content: position,
 	}
-	h.ClientManager.BroadcastAll(msg)
+	h.broadcast(msg)
 }
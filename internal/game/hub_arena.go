@@ -0,0 +1,57 @@
+package game
+
+import (
+	"go.uber.org/zap"
+	"math"
+	"pickup/internal/global"
+)
+
+// arenaSize is the board size picked for a round of a room with SCALE_ARENA.
+type arenaSize struct {
+	Width  int
+	Height int
+}
+
+// scaleArena sizes the board of the round being prepared for its players:
+// ARENA_CELLS_PER_PLAYER cells each, keeping the shape of the room and the
+// sides between ARENA_MIN_SIZE and ARENA_MAX_SIZE. Rooms without
+// SCALE_ARENA, and rounds on a map, keep their size.
+func (h *Hub) scaleArena() {
+	if !h.Config.ScaleArena || h.gameMap.Load() != nil {
+		h.arena.Store(nil)
+		return
+	}
+
+	players := 0
+	for client := range h.ClientManager.GetClients() {
+		if !client.Spectating && h.ClientManager.IsConnected(client.ID) {
+			players++
+		}
+	}
+	players = max(players, 1)
+
+	area := float64(players * global.Dv.GetInt("ARENA_CELLS_PER_PLAYER"))
+	factor := math.Sqrt(area / float64(h.Config.Width*h.Config.Height))
+	size := &arenaSize{
+		Width:  h.clampArenaSide(int(math.Round(float64(h.Config.Width) * factor))),
+		Height: h.clampArenaSide(int(math.Round(float64(h.Config.Height) * factor))),
+	}
+	h.arena.Store(size)
+	zap.S().Infof("hub: %v arena is %dx%d for %d players", h.ID, size.Width, size.Height, players)
+}
+
+func (h *Hub) clampArenaSide(side int) int {
+	return min(max(side, global.Dv.GetInt("ARENA_MIN_SIZE")), global.Dv.GetInt("ARENA_MAX_SIZE"))
+}
+
+// scaleCount scales an obstacle or item count made for a GRIDSIZE square to
+// the area of a scaled arena.
+func (h *Hub) scaleCount(count int) int {
+	size := h.arena.Load()
+	if size == nil || count <= 0 {
+		return count
+	}
+	gridSize := global.Dv.GetInt("GRIDSIZE")
+	scaled := float64(count*size.Width*size.Height) / float64(gridSize*gridSize)
+	return max(int(math.Round(scaled)), 1)
+}
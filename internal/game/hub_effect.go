@@ -0,0 +1,204 @@
+package game
+
+import (
+	"go.uber.org/zap"
+	"pickup/pkg/models"
+	"sort"
+	"time"
+)
+
+// timed status effects a player can carry
+const (
+	EffectSpeed   = "speed"   // two steps per move
+	EffectFrozen  = "frozen"  // no moves at all
+	EffectShield  = "shield"  // blocks hostile effects, nothing can be stolen
+	EffectPhase   = "phase"   // walks through obstacles
+	EffectStunned = "stunned" // no moves at all
+	EffectCursed  = "cursed"  // loses points over time
+)
+
+// hostileEffects are the ones a shield protects against.
+var hostileEffects = map[string]bool{
+	EffectFrozen:  true,
+	EffectStunned: true,
+	EffectCursed:  true,
+}
+
+func init() {
+	itemEffects["speed"] = selfEffect(EffectSpeed)
+	itemEffects["shield"] = selfEffect(EffectShield)
+	itemEffects["phase"] = selfEffect(EffectPhase)
+	itemEffects["freeze"] = freezeOpponents
+}
+
+// selfEffect gives the collecting player the effect for the item's duration.
+func selfEffect(effect string) itemEffect {
+	return func(h *Hub, userId string, def *ItemDefinition) error {
+		h.applyEffect(userId, effect, userId, def.duration())
+		return nil
+	}
+}
+
+// freezeOpponents freezes every other player on the board, teammates are
+// left alone.
+func freezeOpponents(h *Hub, userId string, def *ItemDefinition) error {
+	for _, opponentId := range h.playersOnBoard() {
+		if opponentId != userId && !h.sameTeam(opponentId, userId) {
+			h.applyEffect(opponentId, EffectFrozen, userId, def.duration())
+		}
+	}
+	return nil
+}
+
+// applyEffect starts or renews an effect on a player. Hostile effects
+// bounce off a shield, in which case it returns false.
+func (h *Hub) applyEffect(userId, effect, source string, duration time.Duration) bool {
+	h.effectsMu.Lock()
+	if hostileEffects[effect] && source != userId && h.effects[userId][EffectShield] != nil {
+		h.effectsMu.Unlock()
+		if source != "" {
+			h.sendAlertToUser(source, "The shield blocked your "+effect)
+		}
+		return false
+	}
+	action := &models.Action{
+		ID:        userId,
+		Effect:    effect,
+		Source:    source,
+		ExpiresAt: h.Clock.Now().Add(duration).UnixMilli(),
+	}
+	if h.effects[userId] == nil {
+		h.effects[userId] = make(map[string]*models.Action)
+	}
+	h.effects[userId][effect] = action
+	h.effectsMu.Unlock()
+
+	zap.S().Debugf("hub: %v user %v got %v from %v for %v", h.ID, userId, effect, source, duration)
+	h.broadcast(&models.GameMsg{
+		Type:    models.EffectAppliedType,
+		Content: action,
+	})
+	return true
+}
+
+// removeEffect ends an effect before its time, it returns the removed effect.
+func (h *Hub) removeEffect(userId, effect string) *models.Action {
+	h.effectsMu.Lock()
+	action := h.effects[userId][effect]
+	if action == nil {
+		h.effectsMu.Unlock()
+		return nil
+	}
+	delete(h.effects[userId], effect)
+	if len(h.effects[userId]) == 0 {
+		delete(h.effects, userId)
+	}
+	h.effectsMu.Unlock()
+
+	h.broadcast(&models.GameMsg{
+		Type:    models.EffectExpiredType,
+		Content: action,
+	})
+	return action
+}
+
+func (h *Hub) hasEffect(userId, effect string) bool {
+	h.effectsMu.Lock()
+	defer h.effectsMu.Unlock()
+	return h.effects[userId][effect] != nil
+}
+
+// immobilizedBy returns the effect keeping a player from moving, if any.
+func (h *Hub) immobilizedBy(userId string) string {
+	h.effectsMu.Lock()
+	defer h.effectsMu.Unlock()
+	for _, effect := range []string{EffectFrozen, EffectStunned} {
+		if h.effects[userId][effect] != nil {
+			return effect
+		}
+	}
+	return ""
+}
+
+// playersWithEffect returns the ids of the players carrying an effect.
+func (h *Hub) playersWithEffect(effect string) []string {
+	h.effectsMu.Lock()
+	defer h.effectsMu.Unlock()
+
+	userIds := make([]string, 0)
+	for userId, effects := range h.effects {
+		if effects[effect] != nil {
+			userIds = append(userIds, userId)
+		}
+	}
+	sort.Strings(userIds)
+	return userIds
+}
+
+// expireEffects ends the effects whose time is up.
+func (h *Hub) expireEffects(now time.Time) {
+	h.effectsMu.Lock()
+	expired := make([]*models.Action, 0)
+	for userId, effects := range h.effects {
+		for effect, action := range effects {
+			if action.ExpiresAt <= now.UnixMilli() {
+				delete(effects, effect)
+				expired = append(expired, action)
+			}
+		}
+		if len(effects) == 0 {
+			delete(h.effects, userId)
+		}
+	}
+	h.effectsMu.Unlock()
+
+	// map order is random, keep the messages stable
+	sort.Slice(expired, func(i, j int) bool {
+		if expired[i].ID != expired[j].ID {
+			return expired[i].ID < expired[j].ID
+		}
+		return expired[i].Effect < expired[j].Effect
+	})
+	for _, action := range expired {
+		h.broadcast(&models.GameMsg{
+			Type:    models.EffectExpiredType,
+			Content: action,
+		})
+	}
+}
+
+// SendAllEffectsToClient lets a client joining mid-round know the running effects.
+func (h *Hub) SendAllEffectsToClient(client *Client) {
+	h.effectsMu.Lock()
+	actions := make([]*models.Action, 0)
+	for _, effects := range h.effects {
+		for _, action := range effects {
+			actions = append(actions, action)
+		}
+	}
+	h.effectsMu.Unlock()
+
+	for _, action := range actions {
+		client.Send <- &models.GameMsg{
+			Type:    models.EffectAppliedType,
+			Content: action,
+		}
+	}
+}
+
+func (h *Hub) clearEffects() {
+	h.effectsMu.Lock()
+	defer h.effectsMu.Unlock()
+	h.effects = make(map[string]map[string]*models.Action)
+}
+
+// playersOnBoard returns the ids of the players currently on the board.
+func (h *Hub) playersOnBoard() []string {
+	userIds := make([]string, 0)
+	h.UsersInMap.Range(func(key, value interface{}) bool {
+		userIds = append(userIds, key.(string))
+		return true
+	})
+	sort.Strings(userIds)
+	return userIds
+}
@@ -0,0 +1,21 @@
+package game
+
+import "testing"
+
+func TestFreezeLeavesTeammatesAlone(t *testing.T) {
+	h, _ := newTestHub(t, RoomConfig{ID: "effects", Mode: "classic", Teams: 2}, "a", "teammate", "opponent")
+	h.teams = map[string]string{"a": "red", "teammate": "red", "opponent": "blue"}
+	placePlayer(h, "a", 0, 0)
+	placePlayer(h, "teammate", 1, 0)
+	placePlayer(h, "opponent", 2, 0)
+
+	def, _ := Catalogue.Get("freeze")
+	if err := freezeOpponents(h, "a", def); err != nil {
+		t.Fatal(err)
+	}
+	for userId, want := range map[string]bool{"a": false, "teammate": false, "opponent": true} {
+		if got := h.hasEffect(userId, EffectFrozen); got != want {
+			t.Fatalf("%s frozen %v, want %v", userId, got, want)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package game
+
+import (
+	"go.uber.org/zap"
+	"pickup/internal/global"
+	"pickup/pkg/models"
+	"time"
+)
+
+// holdForPlayers keeps the hub waiting while fewer than the room minimum
+// of players are connected. Once enough players are in, the rest of the
+// waiting phase is cut to a short start countdown.
+func (h *Hub) holdForPlayers() bool {
+	now := h.Clock.Now()
+	connected := h.ClientManager.CountConnected()
+	needed := h.Config.MinPlayers - connected
+
+	if needed > 0 {
+		h.Schedule.StartPhaseAt("waiting", now)
+		if h.PlayersNeeded() != needed {
+			h.setPlayersNeeded(needed)
+			zap.S().Infof("hub: %v waiting for %d more players", h.ID, needed)
+			h.broadcastPlayerGate(needed, connected, time.Time{})
+		}
+		return true
+	}
+
+	if h.PlayersNeeded() > 0 {
+		h.setPlayersNeeded(0)
+		countdown := time.Duration(global.Dv.GetInt("ROUND_START_COUNTDOWN_SEC")) * time.Second
+		startAt := now.Add(min(countdown, h.Schedule.PhaseDuration("waiting")))
+		h.Schedule.EndPhaseAt("waiting", startAt)
+		zap.S().Infof("hub: %v has enough players, round starts at %v", h.ID, startAt)
+		h.broadcastPlayerGate(0, connected, startAt)
+	}
+	return false
+}
+
+func (h *Hub) broadcastPlayerGate(needed int, connected int, startAt time.Time) {
+	gate := &models.PlayerGate{
+		Needed:    needed,
+		Connected: connected,
+		Required:  h.Config.MinPlayers,
+	}
+	if !startAt.IsZero() {
+		gate.StartAt = startAt.UnixMilli()
+	}
+	h.ClientManager.BroadcastAll(&models.GameMsg{
+		Type:    models.PlayerGateType,
+		Content: gate,
+	})
+}
+
+// PlayersNeeded returns how many more players the hub waits for.
+func (h *Hub) PlayersNeeded() int {
+	h.CurrentRound.Mu.RLock()
+	defer h.CurrentRound.Mu.RUnlock()
+	return h.CurrentRound.PlayersNeeded
+}
+
+func (h *Hub) setPlayersNeeded(needed int) {
+	h.CurrentRound.Mu.Lock()
+	defer h.CurrentRound.Mu.Unlock()
+	h.CurrentRound.PlayersNeeded = needed
+}
@@ -0,0 +1,108 @@
+package game
+
+import (
+	"fmt"
+	"go.uber.org/zap"
+	"pickup/internal/global"
+	"pickup/pkg/models"
+	"time"
+)
+
+func init() {
+	itemEffects["stun"] = hazardEffect(EffectStunned)
+	itemEffects["curse"] = hazardEffect(EffectCursed)
+}
+
+// hazardEffect puts the effect on the player who stepped on the hazard, it
+// comes from no one so a shield blocks it.
+func hazardEffect(effect string) itemEffect {
+	return func(h *Hub, userId string, def *ItemDefinition) error {
+		h.applyEffect(userId, effect, "", def.duration())
+		return nil
+	}
+}
+
+// triggerHazard sets off the hazard on the player's cell, if there is one.
+func (h *Hub) triggerHazard(userId string, position *models.Position) {
+	if !h.isRoundRunning() {
+		return
+	}
+	positionString := fmt.Sprintf("%d-%d", position.X, position.Y)
+	value, ok := h.ItemsInMap.Load(positionString)
+	if !ok {
+		return
+	}
+	itemInMap := value.(*models.ItemAction)
+	def, ok := Catalogue.Get(itemInMap.Item.Type)
+	if !ok || def.Kind != ItemKindHazard {
+		return
+	}
+
+	h.ItemsInMap.Delete(positionString)
+	itemInMap.ID = userId
+	h.broadcast(&models.GameMsg{
+		Type:    models.HazardHitType,
+		Content: itemInMap,
+	})
+	zap.S().Debugf("hub: %v user %v triggered %v at %v", h.ID, userId, def.Type, positionString)
+
+	if def.Value < 0 && !h.hasEffect(userId, EffectShield) {
+		h.broadcastSingleScore(userId, h.takeScore(userId, -def.Value))
+	}
+	if def.Effect != "" {
+		if err := h.applyItemEffect(userId, def); err != nil {
+			zap.S().Errorf("hub: %v failed to apply %v: %v", h.ID, def.Effect, err)
+		}
+	}
+}
+
+// takeScore removes up to points from a player, scores don't go below zero.
+func (h *Hub) takeScore(userId string, points int) int {
+	score := 0
+	if value, ok := h.Scores.Load(userId); ok {
+		score = value.(int)
+	}
+	return h.updateScore(userId, -min(points, score))
+}
+
+// tickCurses drains the cursed players at the configured rate.
+func (h *Hub) tickCurses(now time.Time) {
+	interval := time.Duration(global.Dv.GetInt("CURSE_DRAIN_INTERVAL_SEC")) * time.Second
+	if interval <= 0 {
+		return
+	}
+	if h.nextCurseDrain.IsZero() {
+		h.nextCurseDrain = now.Add(interval)
+	}
+	if now.Before(h.nextCurseDrain) {
+		return
+	}
+	h.nextCurseDrain = now.Add(interval)
+
+	for _, userId := range h.playersWithEffect(EffectCursed) {
+		previous, _ := h.Scores.Load(userId)
+		before, _ := previous.(int)
+		// a player at 0 has nothing left to drain
+		if score := h.takeScore(userId, global.Dv.GetInt("CURSE_DRAIN_POINTS")); score != before {
+			h.broadcastSingleScore(userId, score)
+		}
+	}
+}
+
+// passCurse hands the curse of a player over to the one they bumped into,
+// for the time the curse had left.
+func (h *Hub) passCurse(userId, targetId string) {
+	h.effectsMu.Lock()
+	curse := h.effects[userId][EffectCursed]
+	h.effectsMu.Unlock()
+	if curse == nil {
+		return
+	}
+
+	remaining := time.UnixMilli(curse.ExpiresAt).Sub(h.Clock.Now())
+	if remaining <= 0 || !h.applyEffect(targetId, EffectCursed, userId, remaining) {
+		return
+	}
+	h.removeEffect(userId, EffectCursed)
+	zap.S().Debugf("hub: %v user %v passed the curse to %v", h.ID, userId, targetId)
+}
@@ -0,0 +1,42 @@
+package game
+
+import (
+	"testing"
+	"time"
+)
+
+// scoreMessages counts the score updates waiting in the outbox.
+func scoreMessages(h *Hub) int {
+	h.outboxMu.Lock()
+	defer h.outboxMu.Unlock()
+	count := 0
+	for _, queued := range h.outbox {
+		if queued.msg.Type == "score" {
+			count++
+		}
+	}
+	return count
+}
+
+func TestCurseOnlyBroadcastsScoresItDrained(t *testing.T) {
+	setConfig(t, "CURSE_DRAIN_INTERVAL_SEC", 1)
+	setConfig(t, "CURSE_DRAIN_POINTS", 5)
+	h, clock := newTestHub(t, RoomConfig{ID: "curse", Mode: "classic"}, "a", "b")
+	h.updateScore("a", 8)
+	h.applyEffect("a", EffectCursed, "", time.Minute)
+	h.applyEffect("b", EffectCursed, "", time.Minute)
+	h.tickCurses(clock.Now())
+
+	drains := []int{1, 1, 0}
+	for i, want := range drains {
+		h.outbox = nil
+		clock.Advance(time.Second)
+		h.tickCurses(clock.Now())
+		if got := scoreMessages(h); got != want {
+			t.Fatalf("drain %d sent %d scores, want %d", i+1, got, want)
+		}
+	}
+	if score, _ := h.Scores.Load("a"); score != 0 {
+		t.Fatalf("a has %v points after the curse, want 0", score)
+	}
+}
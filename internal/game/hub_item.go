@@ -2,67 +2,164 @@ package game
 
 import (
 	"fmt"
-	"math/rand"
+	"go.uber.org/zap"
 	"pickup/internal/global"
 	"pickup/pkg/models"
+	"time"
 )
 
+// InitObstacles places OBSNUMBER obstacles one at a time, skipping the cells
+// that would cut the free cells in two. Every free cell stays reachable, so
+// do the spawns and items placed on them afterwards.
 func (h *Hub) InitObstacles() {
-	numObstacles := global.Dv.GetInt("OBSNUMBER")
-	for i := 0; i < numObstacles; i++ {
-		x := rand.Intn(global.Dv.GetInt("GRIDSIZE") - 1)
-		y := rand.Intn(global.Dv.GetInt("GRIDSIZE") - 1)
-		positionString := fmt.Sprintf("%d-%d", x, y)
-
-		// check if occupied
-		if _, occupied := h.OccupiedInMap.LoadOrStore(positionString, "obstacle"); !occupied {
-			obstacle := &models.Position{X: x, Y: y}
-			h.OccupiedInMap.Store(positionString, obstacle)
-			h.ObstaclesInMap = append(h.ObstaclesInMap, obstacle)
-		} else {
-			i-- // retry if occupied
+	if gameMap := h.gameMap.Load(); gameMap != nil {
+		h.initMapObstacles(gameMap)
+		return
+	}
+
+	numObstacles := h.scaleCount(global.Dv.GetInt("OBSNUMBER"))
+	width, height := h.GridSize()
+
+	blocked := make(map[models.Position]bool, numObstacles)
+	obstacles := make([]*models.Obstacle, 0, numObstacles)
+	for _, cell := range h.freeCells(h.rng) {
+		if len(blocked) == numObstacles {
+			break
 		}
+		blocked[*cell] = true
+		if !isConnected(width, height, blocked) {
+			delete(blocked, *cell)
+			continue
+		}
+		obstacle := newObstacle(pickObstacleType(h.rng), cell.X, cell.Y)
+		h.OccupiedInMap.Store(fmt.Sprintf("%d-%d", cell.X, cell.Y), obstacle.Position)
+		obstacles = append(obstacles, obstacle)
+	}
+	h.UpdateObstacles(obstacles)
+	if len(blocked) < numObstacles {
+		zap.S().Warnf("hub: %v placed %d of %d obstacles, the rest would cut off part of the grid", h.ID, len(blocked), numObstacles)
 	}
 }
 
-func (h *Hub) GetObstacles() []*models.Position {
+// initMapObstacles places the obstacles of a map, maps are checked to be
+// connected when they are loaded.
+func (h *Hub) initMapObstacles(gameMap *GameMap) {
+	obstacles := make([]*models.Obstacle, 0, len(gameMap.Obstacles))
+	for _, cell := range gameMap.Obstacles {
+		obstacle := newObstacle(ObstacleTypes[0], cell.X, cell.Y)
+		h.OccupiedInMap.Store(fmt.Sprintf("%d-%d", cell.X, cell.Y), obstacle.Position)
+		obstacles = append(obstacles, obstacle)
+	}
+	h.UpdateObstacles(obstacles)
+}
+
+// GetObstacles returns the obstacles of the board. The slice and the
+// obstacles in it are never changed, damage and removals replace them.
+func (h *Hub) GetObstacles() []*models.Obstacle {
 	h.obstaclesMu.RLock()
 	defer h.obstaclesMu.RUnlock()
 	return h.ObstaclesInMap
 }
 
-func (h *Hub) UpdateObstacles(newObstacles []*models.Position) {
+func (h *Hub) UpdateObstacles(newObstacles []*models.Obstacle) {
 	h.obstaclesMu.Lock()
 	defer h.obstaclesMu.Unlock()
 	h.ObstaclesInMap = newObstacles
 }
 
-func (h *Hub) InitActionItems(itemName string, itemType string, itemValue int) {
-	numCoins := global.Dv.GetInt(itemName)
-	for i := 0; i < numCoins; i++ {
-		x := rand.Intn(global.Dv.GetInt("GRIDSIZE") - 1)
-		y := rand.Intn(global.Dv.GetInt("GRIDSIZE") - 1)
-		positionString := fmt.Sprintf("%d-%d", x, y)
-
-		// check if not occupied
-		if _, occupied := h.OccupiedInMap.Load(positionString); !occupied {
-			itemAction := &models.ItemAction{
-				ID:       "",
-				Valid:    true,
-				Item:     &models.Item{Type: itemType, Value: itemValue},
-				Position: &models.Position{X: x, Y: y},
-			}
-			h.ItemsInMap.Store(positionString, itemAction)
-		} else {
-			i-- // retry if occupied
+func (h *Hub) isObstacleAt(x, y int) bool {
+	return h.obstacleAt(x, y) != nil
+}
+
+func (h *Hub) obstacleAt(x, y int) *models.Obstacle {
+	for _, obstacle := range h.GetObstacles() {
+		if obstacle.X == x && obstacle.Y == y {
+			return obstacle
 		}
 	}
+	return nil
+}
+
+// removeObstacle frees the cell of an obstacle, it reports false if there was none.
+func (h *Hub) removeObstacle(x, y int) bool {
+	h.obstaclesMu.Lock()
+	defer h.obstaclesMu.Unlock()
+
+	for i, obstacle := range h.ObstaclesInMap {
+		if obstacle.X == x && obstacle.Y == y {
+			h.ObstaclesInMap = append(h.ObstaclesInMap[:i:i], h.ObstaclesInMap[i+1:]...)
+			// a phasing player may be standing in it
+			h.OccupiedInMap.CompareAndDelete(fmt.Sprintf("%d-%d", x, y), obstacle.Position)
+			return true
+		}
+	}
+	return false
+}
+
+// InitActionItems places the per-round count of an item on cells drawn from rng.
+func (h *Hub) InitActionItems(def *ItemDefinition, rng *lockedRand) []*models.ItemAction {
+	now := h.Clock.Now()
+	count := h.scaleCount(def.Count)
+	created := make([]*models.ItemAction, 0, count)
+	for _, cell := range h.itemCells(rng) {
+		if len(created) == count {
+			break
+		}
+		itemAction := newItemAction(def, cell, now)
+		if _, exists := h.ItemsInMap.LoadOrStore(fmt.Sprintf("%d-%d", cell.X, cell.Y), itemAction); exists {
+			continue
+		}
+		created = append(created, itemAction)
+	}
+	return created
+}
+
+// InitCatalogueItems places the per-round count of every catalogue item on
+// cells drawn from rng.
+func (h *Hub) InitCatalogueItems(rng *lockedRand) []*models.ItemAction {
+	created := make([]*models.ItemAction, 0)
+	if gameMap := h.gameMap.Load(); gameMap != nil {
+		created = append(created, h.initMapItems(gameMap)...)
+	}
+	for _, def := range Catalogue.Items() {
+		created = append(created, h.InitActionItems(def, rng)...)
+	}
+	return created
+}
+
+// initMapItems places the fixed items of a map.
+func (h *Hub) initMapItems(gameMap *GameMap) []*models.ItemAction {
+	now := h.Clock.Now()
+	created := make([]*models.ItemAction, 0, len(gameMap.Items))
+	for _, item := range gameMap.Items {
+		def, ok := Catalogue.Get(item.Type)
+		if !ok {
+			continue
+		}
+		itemAction := newItemAction(def, &models.Position{X: item.X, Y: item.Y}, now)
+		h.ItemsInMap.Store(fmt.Sprintf("%d-%d", item.X, item.Y), itemAction)
+		created = append(created, itemAction)
+	}
+	return created
+}
+
+// expireItems takes the items whose lifetime is over off the board.
+func (h *Hub) expireItems(now time.Time) {
+	h.ItemsInMap.Range(func(key, value interface{}) bool {
+		itemAction := value.(*models.ItemAction)
+		if itemAction.ExpiresAt > 0 && itemAction.ExpiresAt <= now.UnixMilli() {
+			h.ItemsInMap.Delete(key)
+			h.broadcast(&models.GameMsg{
+				Type:    models.ItemExpiredType,
+				Content: itemAction,
+			})
+		}
+		return true
+	})
 }
 
 func (h *Hub) InitAllItems() {
-	h.InitObstacles()
-	h.InitActionItems("COINNUMBER", "coin", 10)
-	h.InitActionItems("DIAMOND", "diamond", 100)
+	h.Mode.SetupMap(h)
 }
 
 func (h *Hub) getItemInMap(positionString string) (*models.ItemAction, error) {
@@ -79,7 +176,16 @@ func (h *Hub) getItemInMap(positionString string) (*models.ItemAction, error) {
 
 func (h *Hub) updateScore(userID string, value int) int {
 	currentScore, _ := h.Scores.LoadOrStore(userID, 0)
+	if value > 0 && !h.canScore(userID) {
+		return currentScore.(int)
+	}
 	newScore := currentScore.(int) + value
 	h.Scores.Store(userID, newScore)
+	h.addTeamScore(userID, value)
+	// tied players are ranked by who got to their score first, losing
+	// points doesn't make a player reach it any sooner
+	if value > 0 {
+		h.scoredAt.Store(userID, h.Clock.Now())
+	}
 	return newScore
 }
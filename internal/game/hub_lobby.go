@@ -0,0 +1,110 @@
+package game
+
+import (
+	"go.uber.org/zap"
+	"pickup/pkg/models"
+	"sort"
+)
+
+// StartLobbyPeriod opens the ready-check, every player has to send
+// playerReady before the round is prepared.
+func (h *Hub) StartLobbyPeriod() {
+	h.CurrentRound.Mu.Lock()
+	defer h.CurrentRound.Mu.Unlock()
+
+	if h.CurrentRound.State != "lobby" {
+		h.CurrentRound.State = "lobby"
+		h.CurrentRound.Ready = make(map[string]bool)
+		for client := range h.ClientManager.GetClients() {
+			client.Spectating = false
+		}
+		zap.S().Infof("hub: %v lobby is open", h.ID)
+		h.BroadcastRoundState("lobby")
+		h.broadcastReadyState()
+	}
+}
+
+// SetPlayerReady marks a player as ready, once every connected player is
+// ready the lobby is cut short.
+func (h *Hub) SetPlayerReady(userId string) {
+	h.CurrentRound.Mu.Lock()
+	defer h.CurrentRound.Mu.Unlock()
+
+	if h.CurrentRound.State != "lobby" || h.CurrentRound.Ready[userId] {
+		return
+	}
+	h.CurrentRound.Ready[userId] = true
+	zap.S().Debugf("hub: %v user %v is ready", h.ID, userId)
+	h.broadcastReadyState()
+	h.startIfAllReady()
+}
+
+// recheckReady runs the ready-check again once a player left the lobby, the
+// players still connected may all be ready.
+func (h *Hub) recheckReady() {
+	h.CurrentRound.Mu.Lock()
+	defer h.CurrentRound.Mu.Unlock()
+
+	if h.CurrentRound.State != "lobby" {
+		return
+	}
+	h.broadcastReadyState()
+	h.startIfAllReady()
+}
+
+// startIfAllReady cuts the lobby short when every connected player is
+// ready, it expects the round lock to be held.
+func (h *Hub) startIfAllReady() {
+	connected := h.connectedPlayerIds()
+	if len(connected) < h.Config.MinPlayers {
+		return
+	}
+	for _, id := range connected {
+		if !h.CurrentRound.Ready[id] {
+			return
+		}
+	}
+
+	zap.S().Infof("hub: %v everyone is ready", h.ID)
+	h.Schedule.StartPhaseAt("preparing", h.Clock.Now())
+}
+
+// moveUnreadyToSpectators leaves the players who did not get ready out of
+// the next round, it expects the round lock to be held.
+func (h *Hub) moveUnreadyToSpectators() {
+	for client := range h.ClientManager.GetClients() {
+		if !h.CurrentRound.Ready[client.ID] {
+			client.Spectating = true
+			zap.S().Infof("hub: %v user %v was not ready and is spectating", h.ID, client.ID)
+		}
+	}
+}
+
+func (h *Hub) connectedPlayerIds() []string {
+	ids := make([]string, 0)
+	for client := range h.ClientManager.GetClients() {
+		if h.ClientManager.IsConnected(client.ID) {
+			ids = append(ids, client.ID)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// broadcastReadyState expects the round lock to be held.
+func (h *Hub) broadcastReadyState() {
+	ready := make([]string, 0, len(h.CurrentRound.Ready))
+	for userId := range h.CurrentRound.Ready {
+		ready = append(ready, userId)
+	}
+	sort.Strings(ready)
+
+	h.ClientManager.BroadcastAll(&models.GameMsg{
+		Type: models.ReadyStateType,
+		Content: &models.ReadyState{
+			Ready:    ready,
+			Players:  h.connectedPlayerIds(),
+			Deadline: h.Schedule.PhaseAt(h.Clock.Now()).End.UnixMilli(),
+		},
+	})
+}
@@ -0,0 +1,37 @@
+package game
+
+import "testing"
+
+func TestLobbyStartsWhenTheLastUnreadyPlayerLeaves(t *testing.T) {
+	h, clock := newTestHub(t, RoomConfig{ID: "lobby", Mode: "classic", Lobby: true, MinPlayers: 2}, "a", "b", "c")
+	for _, id := range []string{"a", "b", "c"} {
+		h.SetClientConnected(id, true)
+	}
+	h.Schedule.StartPhaseAt("lobby", clock.Now())
+	h.StartLobbyPeriod()
+
+	h.SetPlayerReady("a")
+	h.SetPlayerReady("b")
+	if phase := h.Schedule.PhaseAt(clock.Now()).State; phase != "lobby" {
+		t.Fatalf("schedule is at %q while c is not ready", phase)
+	}
+
+	h.SetClientConnected("c", false)
+	if phase := h.Schedule.PhaseAt(clock.Now()).State; phase != "preparing" {
+		t.Fatalf("schedule is at %q once c left, want preparing", phase)
+	}
+}
+
+func TestReconnectKeepsSpectating(t *testing.T) {
+	h, _ := newTestHub(t, RoomConfig{ID: "lobby", Mode: "classic", Lobby: true}, "a")
+	old, _ := h.ClientManager.GetClientByID("a")
+	old.Spectating = true
+
+	client := NewClient("a", h, nil)
+	if h.RegisterClient(client) {
+		t.Fatal("reconnect registered a new client")
+	}
+	if !client.Spectating {
+		t.Fatal("reconnected spectator joined the round")
+	}
+}
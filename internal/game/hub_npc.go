@@ -0,0 +1,263 @@
+package game
+
+import (
+	"fmt"
+	"go.uber.org/zap"
+	"pickup/internal/global"
+	"pickup/pkg/models"
+	"sort"
+	"time"
+)
+
+// entity kinds, rooms set how many of each a round starts with
+const (
+	EntityGuard   = "guard"   // patrols back and forth
+	EntityMonster = "monster" // chases the nearest player
+)
+
+// npc is a server controlled entity and the state of its AI.
+type npc struct {
+	*models.Entity
+	direction models.Position // patrol direction of a guard
+}
+
+// spawnEntities places the guards and monsters of the room for the round
+// being prepared, away from the players where the board allows it.
+func (h *Hub) spawnEntities() {
+	cells := h.entityCells()
+
+	h.entitiesMu.Lock()
+	defer h.entitiesMu.Unlock()
+	for _, spawn := range []struct {
+		kind  string
+		count int
+	}{{EntityGuard, h.Config.Guards}, {EntityMonster, h.Config.Monsters}} {
+		for i := 0; i < spawn.count; i++ {
+			if len(cells) == 0 {
+				zap.S().Warnf("hub: %v no free cell left for a %v", h.ID, spawn.kind)
+				return
+			}
+			cell := cells[0]
+			cells = cells[1:]
+
+			entity := &npc{Entity: &models.Entity{
+				ID:       fmt.Sprintf("%s-%d", spawn.kind, i+1),
+				Kind:     spawn.kind,
+				Position: cell,
+			}}
+			if spawn.kind == EntityGuard {
+				entity.direction = gridDirections[h.rng.Intn(len(gridDirections))]
+			}
+			h.entities[entity.ID] = entity
+			h.OccupiedInMap.Store(fmt.Sprintf("%d-%d", cell.X, cell.Y), cell)
+		}
+	}
+}
+
+// entityCells returns the free cells in spawn order, the ones at least
+// NPC_SPAWN_DISTANCE steps from every player first.
+func (h *Hub) entityCells() []*models.Position {
+	players := make([]*models.Position, 0)
+	h.UsersInMap.Range(func(_, value interface{}) bool {
+		players = append(players, value.(*models.Position))
+		return true
+	})
+
+	minDistance := global.Dv.GetInt("NPC_SPAWN_DISTANCE")
+	far, near := make([]*models.Position, 0), make([]*models.Position, 0)
+	for _, cell := range h.freeCells(h.rng) {
+		isFar := true
+		for _, player := range players {
+			if abs(cell.X-player.X)+abs(cell.Y-player.Y) < minDistance {
+				isFar = false
+				break
+			}
+		}
+		if isFar {
+			far = append(far, cell)
+		} else {
+			near = append(near, cell)
+		}
+	}
+	return append(far, near...)
+}
+
+func (h *Hub) clearEntities() {
+	h.entitiesMu.Lock()
+	defer h.entitiesMu.Unlock()
+	h.entities = make(map[string]*npc)
+}
+
+// entityAt returns the entity on a cell.
+func (h *Hub) entityAt(x, y int) (string, bool) {
+	h.entitiesMu.Lock()
+	defer h.entitiesMu.Unlock()
+	for id, entity := range h.entities {
+		if entity.X == x && entity.Y == y {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// tickEntities moves every entity one cell each NPC_MOVE_INTERVAL_MS, an
+// entity that runs into a player catches them.
+func (h *Hub) tickEntities(now time.Time) {
+	interval := time.Duration(global.Dv.GetInt("NPC_MOVE_INTERVAL_MS")) * time.Millisecond
+	if interval <= 0 {
+		return
+	}
+	if h.nextEntityMove.IsZero() {
+		h.nextEntityMove = now.Add(interval)
+	}
+	if now.Before(h.nextEntityMove) {
+		return
+	}
+	h.nextEntityMove = now.Add(interval)
+
+	players := make(map[models.Position]string)
+	h.UsersInMap.Range(func(key, value interface{}) bool {
+		players[*value.(*models.Position)] = key.(string)
+		return true
+	})
+	blocked := make(map[models.Position]bool)
+	h.OccupiedInMap.Range(func(_, value interface{}) bool {
+		if position := *value.(*models.Position); players[position] == "" {
+			blocked[position] = true
+		}
+		return true
+	})
+
+	type catch struct{ userId, entityId string }
+	catches := make([]catch, 0)
+
+	h.entitiesMu.Lock()
+	ids := make([]string, 0, len(h.entities))
+	for id := range h.entities {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		entity := h.entities[id]
+		next, ok := h.nextEntityStep(entity, blocked, players)
+		if !ok {
+			continue
+		}
+		if userId := players[next]; userId != "" {
+			catches = append(catches, catch{userId: userId, entityId: id})
+			continue
+		}
+
+		delete(blocked, *entity.Position)
+		h.OccupiedInMap.Delete(fmt.Sprintf("%d-%d", entity.X, entity.Y))
+		position := &models.Position{X: next.X, Y: next.Y}
+		blocked[next] = true
+		h.OccupiedInMap.Store(fmt.Sprintf("%d-%d", next.X, next.Y), position)
+		entity.Position = position
+
+		h.broadcast(&models.GameMsg{
+			Type:    models.EntityPositionType,
+			Content: &models.Entity{ID: id, Kind: entity.Kind, Position: position},
+		})
+	}
+	h.entitiesMu.Unlock()
+
+	for _, c := range catches {
+		h.catchPlayer(c.userId, c.entityId)
+	}
+}
+
+// nextEntityStep picks the cell an entity moves to: guards keep their
+// direction and turn around at walls, monsters take the shortest path to a
+// player within NPC_CHASE_RADIUS steps and wander otherwise.
+func (h *Hub) nextEntityStep(entity *npc, blocked map[models.Position]bool, players map[models.Position]string) (models.Position, bool) {
+	width, height := h.GridSize()
+	isOpen := func(cell models.Position) bool {
+		return cell.X >= 0 && cell.X < width && cell.Y >= 0 && cell.Y < height && !blocked[cell]
+	}
+	from := *entity.Position
+
+	if entity.Kind == EntityMonster {
+		targets := make(map[models.Position]bool, len(players))
+		for position := range players {
+			targets[position] = true
+		}
+		if next, ok := pathStep(width, height, blocked, targets, from, global.Dv.GetInt("NPC_CHASE_RADIUS")); ok {
+			return next, true
+		}
+		for _, i := range h.playRng.Perm(len(gridDirections)) {
+			next := models.Position{X: from.X + gridDirections[i].X, Y: from.Y + gridDirections[i].Y}
+			if isOpen(next) && players[next] == "" {
+				return next, true
+			}
+		}
+		return models.Position{}, false
+	}
+
+	// straight on, back, then either side
+	reverse := models.Position{X: -entity.direction.X, Y: -entity.direction.Y}
+	directions := []models.Position{entity.direction, reverse}
+	for _, i := range h.playRng.Perm(len(gridDirections)) {
+		if direction := gridDirections[i]; direction != entity.direction && direction != reverse {
+			directions = append(directions, direction)
+		}
+	}
+	for _, direction := range directions {
+		next := models.Position{X: from.X + direction.X, Y: from.Y + direction.Y}
+		if isOpen(next) {
+			entity.direction = direction
+			return next, true
+		}
+	}
+	return models.Position{}, false
+}
+
+// catchPlayer applies the contact with an entity: the player loses
+// NPC_CONTACT_PENALTY points, is stunned for NPC_STUN_SEC and, with
+// NPC_KNOCKOUT, sent back to a start position. A shield keeps entities off,
+// and a stunned player can't be caught again.
+func (h *Hub) catchPlayer(userId, entityId string) {
+	if !h.isRoundRunning() || h.hasEffect(userId, EffectStunned) || h.hasEffect(userId, EffectShield) {
+		return
+	}
+
+	hit := &models.EntityHit{ID: userId, By: entityId}
+	if points := global.Dv.GetInt("NPC_CONTACT_PENALTY"); points > 0 {
+		hit.Points = points
+		h.broadcastSingleScore(userId, h.takeScore(userId, points))
+	}
+	if global.Dv.GetBool("NPC_KNOCKOUT") {
+		if client, ok := h.ClientManager.GetClientByID(userId); ok {
+			h.removePlayerFromMap(userId)
+			h.InitStartPosition(client)
+			hit.KnockedOut = true
+		}
+	}
+	h.applyEffect(userId, EffectStunned, "", time.Duration(global.Dv.GetInt("NPC_STUN_SEC"))*time.Second)
+
+	h.broadcast(&models.GameMsg{
+		Type:    models.EntityHitType,
+		Content: hit,
+	})
+	zap.S().Debugf("hub: %v %v caught user %v", h.ID, entityId, userId)
+}
+
+func (h *Hub) SendAllEntitiesToClient(client *Client) {
+	h.entitiesMu.Lock()
+	entities := make([]*models.Entity, 0, len(h.entities))
+	for id, entity := range h.entities {
+		entities = append(entities, &models.Entity{ID: id, Kind: entity.Kind, Position: entity.Position})
+	}
+	h.entitiesMu.Unlock()
+
+	s := h.sightOf(client.ID)
+	for _, entity := range entities {
+		msg := &models.GameMsg{
+			Type:    models.EntityPositionType,
+			Content: entity,
+		}
+		if h.visibleTo(client.ID, s, msg) {
+			client.Send <- msg
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package game
+
+import (
+	"fmt"
+	"go.uber.org/zap"
+	"pickup/pkg/models"
+	"slices"
+)
+
+// handleAttack resolves an attack on the cell next to a player, attacks
+// wear down the breakable obstacles and tag the players they hit.
+func (h *Hub) handleAttack(attack *models.Attack) error {
+	if attack.Position == nil {
+		return fmt.Errorf("attack without a target from user %s", attack.ID)
+	}
+	currentPosition, ok := h.UsersInMap.Load(attack.ID)
+	if !ok {
+		return fmt.Errorf("no current position found for user %s", attack.ID)
+	}
+	position := currentPosition.(*models.Position)
+	if abs(attack.X-position.X)+abs(attack.Y-position.Y) != 1 {
+		return fmt.Errorf("user %s attacked (%d, %d), which is not next to them", attack.ID, attack.X, attack.Y)
+	}
+
+	if targetId, onPlayer := h.playerAt(attack.X, attack.Y); onPlayer {
+		h.tagPlayer(attack.ID, targetId)
+		return nil
+	}
+	h.damageObstacle(attack.ID, attack.X, attack.Y)
+	return nil
+}
+
+// tagPlayer tells the mode a player tagged another one, in modes that use tags.
+func (h *Hub) tagPlayer(userId, targetId string) {
+	if tagger, ok := h.Mode.(playerTagger); ok && userId != targetId {
+		tagger.OnPlayerTagged(h, userId, targetId)
+	}
+}
+
+// damageObstacle takes a hit point from the obstacle on a cell, a broken
+// obstacle frees its cell and may leave an item behind.
+func (h *Hub) damageObstacle(userId string, x, y int) {
+	h.obstaclesMu.Lock()
+	index := slices.IndexFunc(h.ObstaclesInMap, func(o *models.Obstacle) bool { return o.X == x && o.Y == y })
+	if index < 0 || h.ObstaclesInMap[index].MaxHP == 0 {
+		h.obstaclesMu.Unlock()
+		if index >= 0 {
+			h.sendAlertToUser(userId, "This obstacle can't be broken")
+		}
+		return
+	}
+	// readers of GetObstacles keep the old slice, the damaged obstacle goes
+	// into a copy
+	damaged := *h.ObstaclesInMap[index]
+	damaged.HP--
+	obstacles := slices.Clone(h.ObstaclesInMap)
+	obstacles[index] = &damaged
+	h.ObstaclesInMap = obstacles
+	hit := &models.ObstacleHit{By: userId, Obstacle: &damaged}
+	h.obstaclesMu.Unlock()
+
+	if hit.HP > 0 {
+		h.broadcast(&models.GameMsg{
+			Type:    models.ObstacleHitType,
+			Content: hit,
+		})
+		return
+	}
+
+	h.removeObstacle(x, y)
+	h.broadcast(&models.GameMsg{
+		Type:    models.ObstacleBrokeType,
+		Content: hit,
+	})
+	zap.S().Debugf("hub: %v user %v broke the %v at (%d, %d)", h.ID, userId, hit.Type, x, y)
+
+	dropper, ok := h.Mode.(obstacleDropper)
+	if def := obstacleType(hit.Type); ok && def != nil && h.playRng.Float64() < def.DropChance {
+		dropper.DropItem(h, x, y)
+	}
+}
+
+// obstacleDropper is implemented by modes whose players collect catalogue
+// items, only there a broken obstacle may leave one behind.
+type obstacleDropper interface {
+	DropItem(h *Hub, x, y int)
+}
+
+// dropItem places a random catalogue item on a freed cell.
+func (h *Hub) dropItem(x, y int) {
+	def := pickSpawnItem(h.playRng, Catalogue.Items())
+	if def == nil {
+		return
+	}
+	itemAction := newItemAction(def, &models.Position{X: x, Y: y}, h.Clock.Now())
+	if _, exists := h.ItemsInMap.LoadOrStore(fmt.Sprintf("%d-%d", x, y), itemAction); exists {
+		return
+	}
+	h.broadcast(&models.GameMsg{
+		Type:    "itemPosition",
+		Content: itemAction,
+	})
+}
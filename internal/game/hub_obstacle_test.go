@@ -0,0 +1,76 @@
+package game
+
+import (
+	"fmt"
+	"testing"
+
+	"pickup/pkg/models"
+)
+
+// putObstacle adds an obstacle of a configured type to the board.
+func putObstacle(h *Hub, obstacleTypeName string, x, y int) {
+	obstacle := newObstacle(obstacleType(obstacleTypeName), x, y)
+	h.OccupiedInMap.Store(fmt.Sprintf("%d-%d", x, y), obstacle.Position)
+	h.UpdateObstacles(append(h.GetObstacles(), obstacle))
+}
+
+func TestBombsDestroyEveryObstacleType(t *testing.T) {
+	h, clock := newTestHub(t, RoomConfig{ID: "obstacles", Mode: "bomberman"})
+	putObstacle(h, "rock", 1, 0)
+	putObstacle(h, "crate", 0, 1)
+
+	bomberman := h.Mode.(*BombermanMode)
+	bomb := &models.Bomb{ID: "bomb", Radius: 2, ExplodeAt: clock.Now().UnixMilli(), Position: &models.Position{X: 0, Y: 0}}
+	bomberman.explode(h, bomb)
+
+	if obstacles := h.GetObstacles(); len(obstacles) != 0 {
+		t.Fatalf("%d obstacles survived the blast", len(obstacles))
+	}
+	if _, occupied := h.OccupiedInMap.Load("1-0"); occupied {
+		t.Fatal("the cell of the rock is still occupied")
+	}
+}
+
+func TestDamagingAnObstacleLeavesEarlierReadsAlone(t *testing.T) {
+	h, _ := newTestHub(t, RoomConfig{ID: "obstacles", Mode: "classic"})
+	putObstacle(h, "crate", 1, 0)
+	before := h.GetObstacles()
+	hp := before[0].HP
+
+	h.damageObstacle("a", 1, 0)
+	if before[0].HP != hp {
+		t.Fatal("damage changed an obstacle handed out before")
+	}
+	if after := h.obstacleAt(1, 0); after == nil || after.HP != hp-1 {
+		t.Fatalf("obstacle after the hit is %+v, want %d HP", after, hp-1)
+	}
+
+	h.damageObstacle("a", 1, 0)
+	h.damageObstacle("a", 1, 0)
+	if h.isObstacleAt(1, 0) {
+		t.Fatalf("crate with %d HP survived %d hits", hp, hp)
+	}
+}
+
+func TestBrokenObstaclesOnlyDropItemsWhereItemsAreCollected(t *testing.T) {
+	previous := ObstacleTypes
+	ObstacleTypes = []*ObstacleDefinition{{Type: "crate", HP: 1, Weight: 1, DropChance: 1}}
+	t.Cleanup(func() { ObstacleTypes = previous })
+
+	for mode, wantItem := range map[string]bool{"classic": true, "bomberman": false} {
+		h, _ := newTestHub(t, RoomConfig{ID: "obstacles", Mode: mode})
+		h.ItemsInMap.Range(func(key, _ interface{}) bool {
+			h.ItemsInMap.Delete(key)
+			return true
+		})
+		putObstacle(h, "crate", 1, 0)
+
+		h.damageObstacle("a", 1, 0)
+		if h.isObstacleAt(1, 0) {
+			t.Fatalf("%s: the crate survived", mode)
+		}
+		if _, dropped := h.ItemsInMap.Load("1-0"); dropped != wantItem {
+			t.Fatalf("%s: item dropped %v, want %v", mode, dropped, wantItem)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package game
+
+import (
+	"go.uber.org/zap"
+	"pickup/internal/global"
+	"pickup/pkg/models"
+	"sort"
+	"time"
+)
+
+// tie-break rules, set with TIE_BREAK
+const (
+	TieBreakNone         = "none"
+	TieBreakFirstToScore = "firstToScore"
+	TieBreakMostItems    = "mostItems" // most items of TIE_BREAK_ITEM
+	TieBreakOvertime     = "overtime"
+)
+
+const RoundEndSuddenDeath = "suddenDeath"
+
+// isRoundRunning reports whether players can play, which is the playing
+// state and its overtime.
+func (h *Hub) isRoundRunning() bool {
+	h.CurrentRound.Mu.RLock()
+	defer h.CurrentRound.Mu.RUnlock()
+	return h.CurrentRound.State == "playing" || h.CurrentRound.State == "overtime"
+}
+
+// canScore reports whether a player may gain points, during overtime only
+// the tied players can.
+func (h *Hub) canScore(userId string) bool {
+	h.CurrentRound.Mu.RLock()
+	defer h.CurrentRound.Mu.RUnlock()
+	if h.CurrentRound.State != "overtime" {
+		return true
+	}
+	for _, tied := range h.CurrentRound.OvertimePlayers {
+		if tied == userId {
+			return true
+		}
+	}
+	return false
+}
+
+// overtimeSpawner is implemented by modes that put fresh items on the board
+// when overtime starts.
+type overtimeSpawner interface {
+	SpawnOvertimeItems(h *Hub) []*models.ItemAction
+}
+
+// overtimeTie returns the players tied at the end of the playing time when
+// the round goes to overtime, or nil when it should end as usual.
+func (h *Hub) overtimeTie() []string {
+	if global.Dv.GetString("TIE_BREAK") != TieBreakOvertime {
+		return nil
+	}
+	// tied teams are decided by who scored first, overtime is per player
+	tied := h.Mode.Winners(h)
+	if len(tied) < 2 || h.hasTeams() {
+		return nil
+	}
+	return tied
+}
+
+// startOvertime turns the end of a tied round into a sudden-death overtime.
+// It runs in the Run loop, which owns the board the mode spawns items on,
+// and does nothing until the playing time is up.
+func (h *Hub) startOvertime() {
+	now := h.Clock.Now()
+	if h.roundState() != "playing" || h.Schedule.PhaseAt(now).State != "ended" {
+		return
+	}
+	tied := h.overtimeTie()
+	if tied == nil {
+		// the round loop ends the round
+		return
+	}
+
+	overtime := time.Duration(global.Dv.GetInt("OVERTIME_SEC")) * time.Second
+	h.Schedule.EndPhaseAt("playing", now.Add(overtime))
+
+	// players tied at 0 may never have scored
+	score, _ := h.Scores.Load(tied[0])
+	tiedScore, _ := score.(int)
+
+	h.CurrentRound.Mu.Lock()
+	h.CurrentRound.State = "overtime"
+	h.CurrentRound.OvertimePlayers = tied
+	h.CurrentRound.OvertimeScore = tiedScore
+	h.BroadcastRoundState("overtime")
+	h.CurrentRound.Mu.Unlock()
+
+	if spawner, ok := h.Mode.(overtimeSpawner); ok {
+		for _, item := range spawner.SpawnOvertimeItems(h) {
+			h.broadcast(&models.GameMsg{
+				Type:    "itemPosition",
+				Content: item,
+			})
+		}
+	}
+	zap.S().Infof("hub: %v round tied between %v, overtime started", h.ID, tied)
+}
+
+func (h *Hub) roundState() string {
+	h.CurrentRound.Mu.RLock()
+	defer h.CurrentRound.Mu.RUnlock()
+	return h.CurrentRound.State
+}
+
+// overtimeDecided reports whether a tied player has scored during overtime.
+func (h *Hub) overtimeDecided() bool {
+	h.CurrentRound.Mu.RLock()
+	defer h.CurrentRound.Mu.RUnlock()
+	if h.CurrentRound.State != "overtime" {
+		return false
+	}
+	for _, userId := range h.CurrentRound.OvertimePlayers {
+		if score, ok := h.Scores.Load(userId); ok && score.(int) > h.CurrentRound.OvertimeScore {
+			return true
+		}
+	}
+	return false
+}
+
+// breakTie narrows tied winners down with the configured rule. Ties still
+// open after overtime go to the player who reached the score first. In team
+// rooms the rule picks between the tied teams.
+func (h *Hub) breakTie(winners []string) []string {
+	if !h.isTie(winners) {
+		return winners
+	}
+
+	var picked []string
+	switch global.Dv.GetString("TIE_BREAK") {
+	case TieBreakFirstToScore, TieBreakOvertime:
+		picked = h.firstToScore(winners)
+	case TieBreakMostItems:
+		picked = h.mostItemsOf(winners, global.Dv.GetString("TIE_BREAK_ITEM"))
+	default:
+		return winners
+	}
+	return h.withTeammates(picked, winners)
+}
+
+// isTie reports whether the winners are more than one player, or more than
+// one team in team rooms.
+func (h *Hub) isTie(winners []string) bool {
+	if !h.hasTeams() {
+		return len(winners) > 1
+	}
+	teams := make(map[string]bool)
+	for _, userId := range winners {
+		teams[h.teamOf(userId)] = true
+	}
+	return len(teams) > 1
+}
+
+// withTeammates adds the teammates among the winners to the picked players.
+func (h *Hub) withTeammates(picked, winners []string) []string {
+	if !h.hasTeams() {
+		return picked
+	}
+	result := make([]string, 0, len(winners))
+	for _, userId := range winners {
+		for _, pickedId := range picked {
+			if userId == pickedId || h.sameTeam(userId, pickedId) {
+				result = append(result, userId)
+				break
+			}
+		}
+	}
+	return result
+}
+
+func (h *Hub) firstToScore(userIds []string) []string {
+	sorted := append([]string(nil), userIds...)
+	scoredAt := func(userId string) time.Time {
+		if at, ok := h.scoredAt.Load(userId); ok {
+			return at.(time.Time)
+		}
+		return time.Time{}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return scoredAt(sorted[i]).Before(scoredAt(sorted[j]))
+	})
+	return []string{sorted[0]}
+}
+
+func (h *Hub) mostItemsOf(userIds []string, itemType string) []string {
+	h.statsMu.Lock()
+	defer h.statsMu.Unlock()
+
+	best := -1
+	winners := make([]string, 0)
+	for _, userId := range userIds {
+		count := 0
+		if stats, ok := h.stats[userId]; ok {
+			count = stats.items[itemType]
+		}
+		switch {
+		case count > best:
+			best = count
+			winners = []string{userId}
+		case count == best:
+			winners = append(winners, userId)
+		}
+	}
+	return winners
+}
@@ -0,0 +1,71 @@
+package game
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+// endPlayingTime moves the schedule of a playing round to its end.
+func endPlayingTime(h *Hub, clock *fakeClock) {
+	h.Schedule.Restart(clock.Now())
+	h.Schedule.StartPhaseAt("ended", clock.Now())
+	h.setRoundState("playing")
+}
+
+func TestBombermanTimeoutTiedAtZeroGoesToOvertime(t *testing.T) {
+	setConfig(t, "TIE_BREAK", TieBreakOvertime)
+	h, clock := newTestHub(t, RoomConfig{ID: "overtime", Mode: "bomberman"}, "a", "b")
+	// survivors who never scored have no score yet
+	placePlayer(h, "a", 0, 0)
+	placePlayer(h, "b", 2, 2)
+	endPlayingTime(h, clock)
+
+	h.updateGameState()
+	if state := h.roundState(); state != "playing" {
+		t.Fatalf("round loop moved a tied round to %q, the Run loop starts overtime", state)
+	}
+
+	h.step()
+	if state := h.roundState(); state != "overtime" {
+		t.Fatalf("round is %q after the tick, want overtime", state)
+	}
+	tied := slices.Clone(h.CurrentRound.OvertimePlayers)
+	slices.Sort(tied)
+	if !slices.Equal(tied, []string{"a", "b"}) || h.CurrentRound.OvertimeScore != 0 {
+		t.Fatalf("overtime between %v from %d, want [a b] from 0", tied, h.CurrentRound.OvertimeScore)
+	}
+}
+
+func TestUntiedRoundEndsWithoutOvertime(t *testing.T) {
+	setConfig(t, "TIE_BREAK", TieBreakOvertime)
+	h, clock := newTestHub(t, RoomConfig{ID: "overtime", Mode: "bomberman"}, "a", "b")
+	placePlayer(h, "a", 0, 0)
+	placePlayer(h, "b", 2, 2)
+	h.updateScore("a", 10)
+	endPlayingTime(h, clock)
+
+	h.step()
+	if state := h.roundState(); state != "playing" {
+		t.Fatalf("tick moved an untied round to %q", state)
+	}
+	h.updateGameState()
+	if state := h.roundState(); state != "ended" {
+		t.Fatalf("round is %q, want ended", state)
+	}
+}
+
+func TestLosingPointsKeepsTheFirstToScoreTieBreak(t *testing.T) {
+	setConfig(t, "TIE_BREAK", TieBreakFirstToScore)
+	h, clock := newTestHub(t, RoomConfig{ID: "overtime", Mode: "classic"}, "a", "b")
+	h.updateScore("a", 15)
+	clock.Advance(time.Second)
+	h.updateScore("b", 10)
+	clock.Advance(time.Second)
+	h.updateScore("a", -5)
+	h.updateScore("a", 0)
+	// a had 10 points before b did
+	if winners := h.breakTie([]string{"a", "b"}); !slices.Equal(winners, []string{"a"}) {
+		t.Fatalf("tie went to %v, want [a]", winners)
+	}
+}
@@ -4,35 +4,55 @@ import (
 	"fmt"
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
-	"math/rand"
-	"pickup/internal/global"
 	"pickup/pkg/models"
 )
 
+// InitStartPosition places a player joining or sent back during the round.
 func (h *Hub) InitStartPosition(client *Client) {
-	tryScope := global.Dv.GetInt("GRIDSIZE") - 1
-	maxAttempts := tryScope * tryScope
-	attempts := 0
+	h.initStartPosition(client, h.playRng)
+}
 
+// initStartPosition places a player with cells drawn from rng, the round
+// setup passes the map rng so a seed gives the same spawns.
+func (h *Hub) initStartPosition(client *Client, rng *lockedRand) {
 	zap.S().Infof("Initializing start position for client %s", client.ID)
 
-	for attempts < maxAttempts {
-		x := rand.Intn(tryScope)
-		y := rand.Intn(tryScope)
-		positionString := fmt.Sprintf("%d-%d", x, y)
-
-		// try to store OccupiedInMap
-		if _, occupied := h.OccupiedInMap.Load(positionString); !occupied {
-			startPosition := &models.PlayerPosition{
-				Valid: true,
-				ID:    client.ID,
-				Position: &models.Position{
-					X: x,
-					Y: y,
-				},
+	// modes like capture the flag start players in their own cells
+	if picker, ok := h.Mode.(startPicker); ok {
+		cells := picker.StartCells(h, client.ID)
+		for _, i := range rng.Perm(len(cells)) {
+			if cell := cells[i]; h.isStartFree(cell.X, cell.Y) {
+				h.placeAtStart(client, cell.X, cell.Y)
+				zap.S().Infof("start position set for client %s at (%d, %d) of the mode", client.ID, cell.X, cell.Y)
+				return
+			}
+		}
+	}
+
+	// maps with spawn points only start players on them
+	if gameMap := h.gameMap.Load(); gameMap != nil && len(gameMap.Spawns) > 0 {
+		order := rng.Perm(len(gameMap.Spawns))
+		for _, i := range order {
+			spawn := gameMap.Spawns[i]
+			if h.isStartFree(spawn.X, spawn.Y) {
+				h.placeAtStart(client, spawn.X, spawn.Y)
+				zap.S().Infof("start position set for client %s at spawn (%d, %d)", client.ID, spawn.X, spawn.Y)
+				return
 			}
-			h.UsersInMap.Store(client.ID, startPosition.Position)
-			h.PositionChan <- startPosition
+		}
+		zap.S().Warnf("no free spawn point for client %s, picking a random cell", client.ID)
+	}
+
+	width, height := h.GridSize()
+	maxAttempts := width * height
+	attempts := 0
+
+	for attempts < maxAttempts {
+		x := rng.Intn(width)
+		y := rng.Intn(height)
+
+		if h.isStartFree(x, y) {
+			h.placeAtStart(client, x, y)
 			zap.S().Infof("start position set for client %s at (%d, %d) after %d attempts", client.ID, x, y, attempts+1)
 			return
 		}
@@ -41,6 +61,28 @@ func (h *Hub) InitStartPosition(client *Client) {
 	zap.S().Errorf("failed to find start position for client %s after %d attempts", client.ID, maxAttempts)
 }
 
+// isStartFree reports whether a player can start on a cell.
+func (h *Hub) isStartFree(x, y int) bool {
+	_, occupied := h.OccupiedInMap.Load(fmt.Sprintf("%d-%d", x, y))
+	return !occupied
+}
+
+func (h *Hub) placeAtStart(client *Client, x, y int) {
+	startPosition := &models.PlayerPosition{
+		Valid: true,
+		ID:    client.ID,
+		Position: &models.Position{
+			X: x,
+			Y: y,
+		},
+	}
+	// placed here instead of through PositionChan, callers hold the
+	// round lock the Run loop may be waiting on
+	h.UsersInMap.Store(client.ID, startPosition.Position)
+	h.OccupiedInMap.Store(fmt.Sprintf("%d-%d", x, y), startPosition.Position)
+	h.broadcastValidPositionToAllClients(startPosition)
+}
+
 func (h *Hub) RecoverStartPosition(client *Client) error {
 	position, ok := h.UsersInMap.Load(client.ID)
 	if !ok {
@@ -75,14 +117,75 @@ func (h *Hub) GetPlayerPositionByUserId(userId string) (*models.PlayerPosition,
 	return playPosition, nil
 }
 
-func IsValidMove(currentPosition, newPosition *models.Position) (string, bool) {
-	if newPosition.X < 0 || newPosition.X >= global.Dv.GetInt("GRIDSIZE") ||
-		newPosition.Y < 0 || newPosition.Y >= global.Dv.GetInt("GRIDSIZE") {
+// removePlayerFromMap takes a player off the board for the rest of the round.
+func (h *Hub) removePlayerFromMap(userId string) {
+	position, ok := h.UsersInMap.LoadAndDelete(userId)
+	if !ok {
+		return
+	}
+	h.releaseCell(position.(*models.Position))
+}
+
+// releaseCell frees the cell a player left, a cell left by a phasing
+// player goes back to its obstacle and a cell shared with a teammate stays
+// theirs. The player must be off UsersInMap already.
+func (h *Hub) releaseCell(position *models.Position) {
+	positionString := fmt.Sprintf("%d-%d", position.X, position.Y)
+	if _, shared := h.playerAt(position.X, position.Y); shared {
+		return
+	}
+	for _, obstacle := range h.GetObstacles() {
+		if obstacle.X == position.X && obstacle.Y == position.Y {
+			h.OccupiedInMap.Store(positionString, obstacle.Position)
+			return
+		}
+	}
+	h.OccupiedInMap.Delete(positionString)
+}
+
+// playerAt returns the player standing on a cell.
+func (h *Hub) playerAt(x, y int) (string, bool) {
+	userId, found := "", false
+	h.UsersInMap.Range(func(key, value interface{}) bool {
+		position := value.(*models.Position)
+		if position.X == x && position.Y == y {
+			userId, found = key.(string), true
+		}
+		return !found
+	})
+	return userId, found
+}
+
+// isPathBlocked reports whether a two step move has no free cell to pass
+// through, single steps have nothing in between.
+func (h *Hub) isPathBlocked(currentPosition, newPosition *models.Position) bool {
+	if abs(newPosition.X-currentPosition.X)+abs(newPosition.Y-currentPosition.Y) < 2 {
+		return false
+	}
+	candidates := []*models.Position{
+		{X: newPosition.X, Y: currentPosition.Y},
+		{X: currentPosition.X, Y: newPosition.Y},
+	}
+	if newPosition.X == currentPosition.X || newPosition.Y == currentPosition.Y {
+		candidates = []*models.Position{{X: (currentPosition.X + newPosition.X) / 2, Y: (currentPosition.Y + newPosition.Y) / 2}}
+	}
+	for _, cell := range candidates {
+		if _, occupied := h.OccupiedInMap.Load(fmt.Sprintf("%d-%d", cell.X, cell.Y)); !occupied {
+			return false
+		}
+	}
+	return true
+}
+
+// IsValidMove checks the move stays on a width x height grid and within maxSteps.
+func IsValidMove(currentPosition, newPosition *models.Position, width, height, maxSteps int) (string, bool) {
+	if newPosition.X < 0 || newPosition.X >= width ||
+		newPosition.Y < 0 || newPosition.Y >= height {
 		return "The move is out of grid", false
 	}
 
-	if abs(newPosition.X-currentPosition.X)+abs(newPosition.Y-currentPosition.Y) > 1 {
-		return "The move is over 1 step (you can move only 1 step)", false
+	if abs(newPosition.X-currentPosition.X)+abs(newPosition.Y-currentPosition.Y) > maxSteps {
+		return fmt.Sprintf("The move is over %d step (you can move only %d step)", maxSteps, maxSteps), false
 	}
 	return "", true
 }
@@ -8,9 +8,18 @@ import (
 )
 
 type Round struct {
-	Hub   *Hub
-	State string // "waiting", "cleanup", "preparing", "playing", "ended"
-	Mu    sync.RWMutex
+	Hub             *Hub
+	State           string          // "waiting", "cleanup", "lobby", "preparing", "playing", "overtime", "ended"
+	EndReason       string          // why the round ended, see the RoundEnd* constants
+	StartItems      int             // items to collect on the board when play started
+	StartPlayers    int             // players on the board when play started
+	OvertimePlayers []string        // tied players, the only ones scoring in overtime
+	OvertimeScore   int             // the tied score overtime started from
+	PlayersNeeded   int             // players missing before the round can start
+	Ready           map[string]bool // players who confirmed the lobby ready-check
+	Seed            int64           // seeds the map generation of the round
+	NextSeed        *int64          // replaces the random seed of the next round
+	Mu              sync.RWMutex
 }
 
 func (h *Hub) NewRound() *Round {
@@ -21,57 +30,68 @@ func (h *Hub) NewRound() *Round {
 }
 
 func (h *Hub) ManageGameRounds() {
+	// every hub runs its own timeline, starting when the loop starts
+	h.Schedule.Restart(h.Clock.Now())
 	h.initializeGameState()
 
-	ticker := time.NewTicker(100 * time.Millisecond)
+	ticker := h.Clock.NewTicker(100 * time.Millisecond)
 	defer ticker.Stop()
 
 	for {
 		select {
-		case <-ticker.C:
+		case <-h.quit:
+			return
+		case <-ticker.C():
 			h.updateGameState()
 		}
 	}
 }
 
+func (h *Hub) tickGameMode() {
+	if h.isRoundRunning() {
+		h.Mode.OnTick(h, h.Clock.Now())
+	}
+}
+
 func (h *Hub) initializeGameState() {
-	now := time.Now()
-	second := now.Second()
+	h.applyPhase(h.Schedule.PhaseAt(h.Clock.Now()).State)
+	h.BroadcastCountdown()
+}
+
+func (h *Hub) updateGameState() {
+	phase := h.Schedule.PhaseAt(h.Clock.Now())
+	state := h.CurrentRound.State
 
 	switch {
-	case second < 5:
-		h.StartWaitingPeriod()
-	case second < 7:
-		h.CleanUpPeriod()
-	case second < 10:
-		h.StartPreparePeriod()
-	case second < 59:
-		h.StartGameRound()
+	case state == "waiting" && h.holdForPlayers():
+		// not enough players to start a round
+	case state == phase.State:
+	case state == "overtime" && phase.State == "playing":
+		// overtime runs on an extended playing phase
+	case state == "playing" && phase.State == "ended" && h.overtimeTie() != nil:
+		// the Run loop starts the overtime
 	default:
-		h.EndGameRound()
+		h.applyPhase(phase.State)
 	}
 
 	h.BroadcastCountdown()
 }
 
-func (h *Hub) updateGameState() {
-	now := time.Now()
-	second := now.Second()
-
-	switch {
-	case second == 0 && h.CurrentRound.State != "waiting":
+func (h *Hub) applyPhase(state string) {
+	switch state {
+	case "waiting":
 		h.StartWaitingPeriod()
-	case second == 5 && h.CurrentRound.State != "cleanup":
+	case "cleanup":
 		h.CleanUpPeriod()
-	case second == 7 && h.CurrentRound.State != "preparing":
+	case "lobby":
+		h.StartLobbyPeriod()
+	case "preparing":
 		h.StartPreparePeriod()
-	case second == 10 && h.CurrentRound.State != "playing":
+	case "playing":
 		h.StartGameRound()
-	case second == 59 && h.CurrentRound.State != "ended":
+	case "ended":
 		h.EndGameRound()
 	}
-
-	h.BroadcastCountdown()
 }
 
 func (h *Hub) StartWaitingPeriod() {
@@ -101,6 +121,9 @@ func (h *Hub) StartPreparePeriod() {
 	defer h.CurrentRound.Mu.Unlock()
 
 	if h.CurrentRound.State != "preparing" {
+		if h.CurrentRound.State == "lobby" {
+			h.moveUnreadyToSpectators()
+		}
 		h.CurrentRound.State = "preparing"
 		zap.S().Infof("hub: %v round preparing", h.ID)
 		h.InitializeRoundState()
@@ -111,7 +134,12 @@ func (h *Hub) StartPreparePeriod() {
 func (h *Hub) InitializeRoundState() {
 	zap.S().Debugf("hub: %v initializing round started", h.ID)
 
+	h.seedRound()
 	h.ClearPreviousRoundData()
+	if gameMap := h.nextMap(); gameMap != nil {
+		zap.S().Infof("hub: %v round map %v", h.ID, gameMap.Name)
+	}
+	h.scaleArena()
 	h.InitAllItems()
 
 	// clear disconnect client
@@ -120,11 +148,24 @@ func (h *Hub) InitializeRoundState() {
 		h.ClientManager.RemoveClient(client)
 	}
 
-	// reset position
-	for client, _ := range h.ClientManager.GetClients() {
-		client.Hub.InitStartPosition(client)
+	players := make([]*Client, 0)
+	for _, client := range h.ClientManager.SortedClients() {
+		if !client.Spectating {
+			players = append(players, client)
+		}
+	}
+	h.assignTeams(players)
+
+	// reset position, in a stable order so a seed gives the same spawns
+	for _, client := range h.ClientManager.SortedClients() {
+		if client.Spectating {
+			client.AllowJoinGame = false
+			continue
+		}
+		h.initStartPosition(client, h.rng)
 		client.AllowJoinGame = true
 	}
+	h.spawnEntities()
 
 	// send new game state to clients
 	for client, _ := range h.ClientManager.GetClients() {
@@ -134,12 +175,46 @@ func (h *Hub) InitializeRoundState() {
 	zap.S().Debugf("hub: %v initializing round completed", h.ID)
 }
 
+// seedRound picks the seed of the round being prepared and reseeds the map
+// generation with it.
+func (h *Hub) seedRound() {
+	seed := h.seeds.Int63()
+	if h.CurrentRound.NextSeed != nil {
+		seed = *h.CurrentRound.NextSeed
+		h.CurrentRound.NextSeed = nil
+	}
+	h.CurrentRound.Seed = seed
+	h.rng.Seed(seed)
+	// drawn before the map, what happens in the round doesn't shift its layout
+	h.playRng.Seed(h.rng.Int63())
+	zap.S().Infof("hub: %v round seed %d", h.ID, seed)
+}
+
+// SetNextSeed makes the next round generate its map from the given seed.
+func (h *Hub) SetNextSeed(seed int64) {
+	h.CurrentRound.Mu.Lock()
+	defer h.CurrentRound.Mu.Unlock()
+	h.CurrentRound.NextSeed = &seed
+}
+
+// RoundSeed returns the seed of the current round.
+func (h *Hub) RoundSeed() int64 {
+	h.CurrentRound.Mu.RLock()
+	defer h.CurrentRound.Mu.RUnlock()
+	return h.CurrentRound.Seed
+}
+
 func (h *Hub) StartGameRound() {
 	h.CurrentRound.Mu.Lock()
 	defer h.CurrentRound.Mu.Unlock()
 
 	if h.CurrentRound.State != "playing" {
 		h.CurrentRound.State = "playing"
+		h.CurrentRound.EndReason = ""
+		h.CurrentRound.OvertimePlayers = nil
+		h.CurrentRound.StartItems = h.countCollectableItems()
+		h.CurrentRound.StartPlayers = h.countPlayersInMap()
+		h.resetStats(h.Clock.Now())
 		zap.S().Infof("hub: %v round is starting", h.ID)
 		h.BroadcastRoundState("playing")
 	}
@@ -147,84 +222,79 @@ func (h *Hub) StartGameRound() {
 
 func (h *Hub) EndGameRound() {
 	h.CurrentRound.Mu.Lock()
-	defer h.CurrentRound.Mu.Unlock()
-
-	if h.CurrentRound.State != "ended" {
-		h.CurrentRound.State = "ended"
-		for client, _ := range h.ClientManager.GetClients() {
-			client.AllowJoinGame = false
-		}
-		h.BroadcastRoundState("ended")
+	if h.CurrentRound.State == "ended" {
+		h.CurrentRound.Mu.Unlock()
+		return
+	}
+	h.CurrentRound.State = "ended"
+	if h.CurrentRound.EndReason == "" {
+		h.CurrentRound.EndReason = RoundEndTimeUp
+	}
+	for client, _ := range h.ClientManager.GetClients() {
+		client.AllowJoinGame = false
 	}
+	result := h.buildRoundResult(h.CurrentRound.EndReason, h.Clock.Now())
+	zap.S().Infof("hub: %v round ended, winners: %v", h.ID, result.Winners)
+	h.publishRoundResult(result)
+	h.BroadcastRoundState("ended")
+	h.CurrentRound.Mu.Unlock()
+
+	h.notifyRoundResult(result)
 }
 
 func (h *Hub) BroadcastRoundState(state string) {
-	now := time.Now()
-	var endTime time.Time
+	now := h.Clock.Now()
+	endTime := h.Schedule.PhaseAt(now).End
 
-	switch state {
-	case "waiting":
-		endTime = time.Date(now.Year(), now.Month(), now.Day(), now.Hour(), now.Minute(), 5, 0, now.Location())
-	case "cleanup":
-		endTime = time.Date(now.Year(), now.Month(), now.Day(), now.Hour(), now.Minute(), 10, 0, now.Location())
-	case "preparing":
-		endTime = time.Date(now.Year(), now.Month(), now.Day(), now.Hour(), now.Minute(), 15, 0, now.Location())
-	case "playing":
-		endTime = time.Date(now.Year(), now.Month(), now.Day(), now.Hour(), now.Minute(), 59, 0, now.Location())
-	case "ended":
-		endTime = time.Date(now.Year(), now.Month(), now.Day(), now.Hour(), now.Minute()+1, 0, 0, now.Location())
+	content := map[string]interface{}{
+		"state":       state,
+		"currentTime": now,
+		"endTime":     endTime,
+	}
+	if state == "ended" {
+		content["reason"] = h.CurrentRound.EndReason
+	}
+	if state == "overtime" {
+		content["players"] = h.CurrentRound.OvertimePlayers
+	}
+	if state == "preparing" {
+		content["seed"] = h.CurrentRound.Seed
+		content["width"], content["height"] = h.GridSize()
 	}
 
 	msg := &models.GameMsg{
-		Type: "roundState",
-		Content: map[string]interface{}{
-			"state":       state,
-			"currentTime": now,
-			"endTime":     endTime,
-		},
+		Type:    "roundState",
+		Content: content,
 	}
 	h.ClientManager.BroadcastAll(msg)
 }
 
 func (h *Hub) ClearPreviousRoundData() {
 	h.OccupiedInMap = sync.Map{}
-	h.ObstaclesInMap = make([]*models.Position, 0)
+	h.UpdateObstacles(make([]*models.Obstacle, 0))
 	h.ItemsInMap = sync.Map{}
 	h.UsersInMap = sync.Map{}
 	h.Scores = sync.Map{}
+	h.scoredAt = sync.Map{}
+	h.TeamScores = sync.Map{}
+	h.clearEffects()
+	h.clearEntities()
+	h.clearViews()
 }
 
 func (h *Hub) BroadcastCountdown() {
-	now := time.Now()
-	second := now.Second()
-	var target time.Time
-	var currentState string
+	now := h.Clock.Now()
+	phase := h.Schedule.PhaseAt(now)
 
-	switch {
-	case second < 5:
-		target = time.Date(now.Year(), now.Month(), now.Day(), now.Hour(), now.Minute(), 5, 0, now.Location())
-		currentState = "waiting"
-	case second < 7:
-		target = time.Date(now.Year(), now.Month(), now.Day(), now.Hour(), now.Minute(), 7, 0, now.Location())
-		currentState = "cleanup"
-	case second < 10:
-		target = time.Date(now.Year(), now.Month(), now.Day(), now.Hour(), now.Minute(), 10, 0, now.Location())
-		currentState = "preparing"
-	case second < 59:
-		target = time.Date(now.Year(), now.Month(), now.Day(), now.Hour(), now.Minute(), 59, 0, now.Location())
-		currentState = "playing"
-	default:
-		target = time.Date(now.Year(), now.Month(), now.Day(), now.Hour(), now.Minute()+1, 0, 0, now.Location())
-		currentState = "ended"
+	currentState := phase.State
+	if phase.State == "playing" && h.CurrentRound.State == "overtime" {
+		currentState = "overtime"
 	}
 
-	remaining := target.Sub(now)
-	stateRemainingTime := int(remaining.Seconds()) % 60
-
 	msg := &models.GameMsg{
 		Type: "countdown",
 		Content: map[string]interface{}{
-			"remainingTime": stateRemainingTime,
+			"remainingTime": int(phase.End.Sub(now).Seconds()),
 			"currentState":  currentState,
 		},
 	}
@@ -232,6 +302,5 @@ func (h *Hub) BroadcastCountdown() {
 }
 
 func (h *Hub) GetNextRoundStartTime() time.Time {
-	now := time.Now()
-	return time.Date(now.Year(), now.Month(), now.Day(), now.Hour(), now.Minute()+1, 0, 0, now.Location())
+	return h.Schedule.NextRoundStart(h.Clock.Now())
 }
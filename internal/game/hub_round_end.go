@@ -0,0 +1,125 @@
+package game
+
+import (
+	"go.uber.org/zap"
+	"pickup/internal/global"
+	"pickup/pkg/models"
+)
+
+// reasons sent with the "ended" roundState
+const (
+	RoundEndTimeUp            = "timeUp"
+	RoundEndScoreTarget       = "scoreTarget"
+	RoundEndAllItemsCollected = "allItemsCollected"
+	RoundEndLastPlayer        = "lastPlayerStanding"
+)
+
+// checkRoundEnd ends the round early once one of the configured end
+// conditions is met.
+func (h *Hub) checkRoundEnd() {
+	if !h.isRoundRunning() {
+		return
+	}
+
+	if h.overtimeDecided() {
+		h.EndRoundEarly(RoundEndSuddenDeath)
+		return
+	}
+
+	if reason, ok := h.roundEndCondition(); ok {
+		h.EndRoundEarly(reason)
+	}
+}
+
+func (h *Hub) roundEndCondition() (string, bool) {
+	if target := global.Dv.GetInt("ROUND_END_SCORE_TARGET"); target > 0 {
+		reached := false
+		// team rooms play to a team total
+		scores := &h.Scores
+		if h.hasTeams() {
+			scores = &h.TeamScores
+		}
+		scores.Range(func(_, score interface{}) bool {
+			reached = score.(int) >= target
+			return !reached
+		})
+		if reached {
+			return RoundEndScoreTarget, true
+		}
+	}
+
+	h.CurrentRound.Mu.RLock()
+	startItems, startPlayers := h.CurrentRound.StartItems, h.CurrentRound.StartPlayers
+	h.CurrentRound.Mu.RUnlock()
+
+	// with the spawner on, an empty board only lasts until the next spawn
+	spawning := global.Dv.GetInt("ITEM_SPAWN_INTERVAL_SEC") > 0
+	if global.Dv.GetBool("ROUND_END_ON_ALL_ITEMS_COLLECTED") && !spawning && startItems > 0 && h.countCollectableItems() == 0 {
+		return RoundEndAllItemsCollected, true
+	}
+
+	// a round that started with a single player is played out
+	if global.Dv.GetBool("ROUND_END_ON_LAST_PLAYER") && startPlayers > 1 && h.countActivePlayers() <= 1 {
+		return RoundEndLastPlayer, true
+	}
+
+	return "", false
+}
+
+// EndRoundEarly ends a running round now and shifts the schedule so the
+// following phases start from here.
+func (h *Hub) EndRoundEarly(reason string) {
+	h.CurrentRound.Mu.Lock()
+	if h.CurrentRound.State != "playing" && h.CurrentRound.State != "overtime" {
+		h.CurrentRound.Mu.Unlock()
+		return
+	}
+	h.CurrentRound.EndReason = reason
+	h.CurrentRound.Mu.Unlock()
+
+	h.Schedule.StartPhaseAt("ended", h.Clock.Now())
+	zap.S().Infof("hub: %v round ended early due to %v", h.ID, reason)
+	h.EndGameRound()
+}
+
+func (h *Hub) countItems() int {
+	count := 0
+	h.ItemsInMap.Range(func(_, _ interface{}) bool {
+		count++
+		return true
+	})
+	return count
+}
+
+// countCollectableItems counts the items left to pick up, hazards don't count.
+func (h *Hub) countCollectableItems() int {
+	count := 0
+	h.ItemsInMap.Range(func(_, value interface{}) bool {
+		if def, ok := Catalogue.Get(value.(*models.ItemAction).Item.Type); !ok || def.Kind != ItemKindHazard {
+			count++
+		}
+		return true
+	})
+	return count
+}
+
+func (h *Hub) countPlayersInMap() int {
+	count := 0
+	h.UsersInMap.Range(func(_, _ interface{}) bool {
+		count++
+		return true
+	})
+	return count
+}
+
+// countActivePlayers counts the connected players still on the board.
+func (h *Hub) countActivePlayers() int {
+	count := 0
+	h.UsersInMap.Range(func(key, _ interface{}) bool {
+		if h.ClientManager.IsConnected(key.(string)) {
+			count++
+		}
+		return true
+	})
+	return count
+}
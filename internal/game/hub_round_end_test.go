@@ -0,0 +1,38 @@
+package game
+
+import "testing"
+
+func TestEmptyBoardOnlyEndsTheRoundWithoutSpawns(t *testing.T) {
+	setConfig(t, "ROUND_END_ON_ALL_ITEMS_COLLECTED", true)
+	setConfig(t, "ROUND_END_ON_LAST_PLAYER", false)
+	h, _ := newTestHub(t, RoomConfig{ID: "end", Mode: "classic"})
+	h.CurrentRound.StartItems = 3
+
+	setConfig(t, "ITEM_SPAWN_INTERVAL_SEC", 3)
+	if reason, ok := h.roundEndCondition(); ok {
+		t.Fatalf("round ended for %s while items still spawn", reason)
+	}
+
+	setConfig(t, "ITEM_SPAWN_INTERVAL_SEC", 0)
+	if reason, ok := h.roundEndCondition(); !ok || reason != RoundEndAllItemsCollected {
+		t.Fatalf("round end is %q, %v without spawns, want %s", reason, ok, RoundEndAllItemsCollected)
+	}
+}
+
+func TestTeamRoomsPlayToATeamScoreTarget(t *testing.T) {
+	setConfig(t, "ROUND_END_SCORE_TARGET", 10)
+	setConfig(t, "ROUND_END_ON_ALL_ITEMS_COLLECTED", false)
+	setConfig(t, "ROUND_END_ON_LAST_PLAYER", false)
+	h, _ := newTestHub(t, RoomConfig{ID: "end", Mode: "classic", Teams: 2})
+	h.Scores.Store("a", 6)
+	h.Scores.Store("b", 6)
+	h.TeamScores.Store("red", 9)
+
+	if reason, ok := h.roundEndCondition(); ok {
+		t.Fatalf("round ended for %s before a team reached the target", reason)
+	}
+	h.TeamScores.Store("red", 12)
+	if reason, ok := h.roundEndCondition(); !ok || reason != RoundEndScoreTarget {
+		t.Fatalf("round end is %q, %v, want %s", reason, ok, RoundEndScoreTarget)
+	}
+}
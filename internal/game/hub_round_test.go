@@ -0,0 +1,73 @@
+package game
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	"pickup/pkg/models"
+)
+
+// prepareRound connects the players and prepares a round from seed.
+func prepareRound(t *testing.T, seed int64, players ...string) *Hub {
+	t.Helper()
+	h, _ := newTestHub(t, RoomConfig{ID: "seed", Mode: "classic"}, players...)
+	for _, id := range players {
+		h.SetClientConnected(id, true)
+	}
+	h.SetNextSeed(seed)
+	h.StartPreparePeriod()
+	return h
+}
+
+// layout lists the obstacles, items and players of the board.
+func layout(h *Hub) []string {
+	cells := make([]string, 0)
+	for _, obstacle := range h.GetObstacles() {
+		cells = append(cells, fmt.Sprintf("obstacle %d-%d", obstacle.X, obstacle.Y))
+	}
+	h.ItemsInMap.Range(func(key, value interface{}) bool {
+		cells = append(cells, fmt.Sprintf("%s %s", value.(*models.ItemAction).Item.Type, key))
+		return true
+	})
+	h.UsersInMap.Range(func(key, value interface{}) bool {
+		position := value.(*models.Position)
+		cells = append(cells, fmt.Sprintf("player %s %d-%d", key, position.X, position.Y))
+		return true
+	})
+	slices.Sort(cells)
+	return cells
+}
+
+func TestSeedRegeneratesTheRound(t *testing.T) {
+	first := prepareRound(t, 42, "a", "b", "c")
+	second := prepareRound(t, 42, "a", "b", "c")
+	if first.RoundSeed() != 42 {
+		t.Fatalf("round seed is %d, want 42", first.RoundSeed())
+	}
+	if !slices.Equal(layout(first), layout(second)) {
+		t.Fatalf("seed 42 generated\n%v\nand\n%v", layout(first), layout(second))
+	}
+
+	other := prepareRound(t, 43, "a", "b", "c")
+	if slices.Equal(layout(first), layout(other)) {
+		t.Fatal("seeds 42 and 43 generated the same round")
+	}
+}
+
+func TestRoundPlayDoesNotDrawFromTheMapRng(t *testing.T) {
+	quiet := prepareRound(t, 7, "a", "b")
+	busy := prepareRound(t, 7, "a", "b")
+
+	// a late join and a knockout draw their start cells while playing
+	late := addClient(busy, "late")
+	busy.InitStartPosition(late)
+	a, _ := busy.ClientManager.GetClientByID("a")
+	busy.removePlayerFromMap("a")
+	busy.InitStartPosition(a)
+	busy.dropItem(0, 0)
+
+	if quiet.rng.Int63() != busy.rng.Int63() {
+		t.Fatal("play in the round moved the map rng")
+	}
+}
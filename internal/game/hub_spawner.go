@@ -0,0 +1,116 @@
+package game
+
+import (
+	"fmt"
+	"pickup/internal/global"
+	"pickup/pkg/models"
+	"time"
+)
+
+// tickItemSpawner adds an item at the configured rate while the round is
+// playing, up to the configured number of items on the board.
+func (h *Hub) tickItemSpawner(now time.Time) {
+	interval := time.Duration(global.Dv.GetInt("ITEM_SPAWN_INTERVAL_SEC")) * time.Second
+	if interval <= 0 {
+		return
+	}
+	if h.nextItemSpawn.IsZero() {
+		h.nextItemSpawn = now.Add(interval)
+	}
+	if now.Before(h.nextItemSpawn) {
+		return
+	}
+	h.nextItemSpawn = now.Add(interval)
+
+	if h.countItems() >= h.scaleCount(global.Dv.GetInt("ITEM_SPAWN_CAP")) {
+		return
+	}
+	def := pickSpawnItem(h.playRng, Catalogue.Items())
+	if def == nil {
+		return
+	}
+	cells := h.itemCells(h.playRng)
+	if len(cells) == 0 {
+		return
+	}
+	cell := cells[0]
+
+	itemAction := newItemAction(def, cell, now)
+	h.ItemsInMap.Store(fmt.Sprintf("%d-%d", cell.X, cell.Y), itemAction)
+	h.broadcast(&models.GameMsg{
+		Type:    "itemPosition",
+		Content: itemAction,
+	})
+}
+
+// pickSpawnItem picks a catalogue item in proportion to its spawn weight.
+func pickSpawnItem(rng *lockedRand, items []*ItemDefinition) *ItemDefinition {
+	total := 0
+	for _, def := range items {
+		total += max(def.Weight, 0)
+	}
+	if total == 0 {
+		return nil
+	}
+	pick := rng.Intn(total)
+	for _, def := range items {
+		if pick < max(def.Weight, 0) {
+			return def
+		}
+		pick -= max(def.Weight, 0)
+	}
+	return nil
+}
+
+// itemCells returns the free cells random items can be placed on, the item
+// zones when the map has some.
+func (h *Hub) itemCells(rng *lockedRand) []*models.Position {
+	cells := h.freeCells(rng)
+	gameMap := h.gameMap.Load()
+	if gameMap == nil || len(gameMap.ItemZones) == 0 {
+		return cells
+	}
+
+	zones := make(map[models.Position]bool, len(gameMap.ItemZones))
+	for _, zone := range gameMap.ItemZones {
+		zones[*zone] = true
+	}
+	inZones := make([]*models.Position, 0, len(gameMap.ItemZones))
+	for _, cell := range cells {
+		if zones[*cell] {
+			inZones = append(inZones, cell)
+		}
+	}
+	return inZones
+}
+
+// freeCells returns the cells without an obstacle, an item or a player, in
+// an order drawn from rng.
+func (h *Hub) freeCells(rng *lockedRand) []*models.Position {
+	players := make(map[string]bool)
+	h.UsersInMap.Range(func(_, value interface{}) bool {
+		position := value.(*models.Position)
+		players[fmt.Sprintf("%d-%d", position.X, position.Y)] = true
+		return true
+	})
+
+	width, height := h.GridSize()
+	cells := make([]*models.Position, 0)
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			positionString := fmt.Sprintf("%d-%d", x, y)
+			if players[positionString] {
+				continue
+			}
+			if _, occupied := h.OccupiedInMap.Load(positionString); occupied {
+				continue
+			}
+			if _, hasItem := h.ItemsInMap.Load(positionString); hasItem {
+				continue
+			}
+			cells = append(cells, &models.Position{X: x, Y: y})
+		}
+	}
+	rng.Shuffle(len(cells), func(i, j int) { cells[i], cells[j] = cells[j], cells[i] })
+	return cells
+}
@@ -0,0 +1,214 @@
+package game
+
+import (
+	"pickup/pkg/models"
+	"slices"
+	"sort"
+	"time"
+)
+
+// playerStats are the per-player counters of the running round.
+type playerStats struct {
+	items        map[string]int // map[itemType]count
+	moves        int
+	invalidMoves int
+	connected    time.Duration
+	connectedAt  time.Time // zero while the player is disconnected
+}
+
+func (h *Hub) getPlayerStats(userId string) *playerStats {
+	stats, ok := h.stats[userId]
+	if !ok {
+		stats = &playerStats{items: make(map[string]int)}
+		h.stats[userId] = stats
+	}
+	return stats
+}
+
+// resetStats starts counting a new round, connected players start their
+// connection time now.
+func (h *Hub) resetStats(now time.Time) {
+	h.statsMu.Lock()
+	defer h.statsMu.Unlock()
+
+	h.stats = make(map[string]*playerStats)
+	for client := range h.ClientManager.GetClients() {
+		if h.ClientManager.IsConnected(client.ID) {
+			h.getPlayerStats(client.ID).connectedAt = now
+		}
+	}
+}
+
+func (h *Hub) recordMove(userId string, valid bool) {
+	h.statsMu.Lock()
+	defer h.statsMu.Unlock()
+
+	if valid {
+		h.getPlayerStats(userId).moves++
+	} else {
+		h.getPlayerStats(userId).invalidMoves++
+	}
+}
+
+func (h *Hub) recordItemCollected(userId string, itemType string) {
+	h.statsMu.Lock()
+	defer h.statsMu.Unlock()
+	h.getPlayerStats(userId).items[itemType]++
+}
+
+func (h *Hub) recordConnection(userId string, connected bool, now time.Time) {
+	h.statsMu.Lock()
+	defer h.statsMu.Unlock()
+
+	stats := h.getPlayerStats(userId)
+	switch {
+	case connected && stats.connectedAt.IsZero():
+		stats.connectedAt = now
+	case !connected && !stats.connectedAt.IsZero():
+		stats.connected += now.Sub(stats.connectedAt)
+		stats.connectedAt = time.Time{}
+	}
+}
+
+// SetClientConnected updates the connection state of a client and its
+// connection time in the round stats.
+func (h *Hub) SetClientConnected(userId string, connected bool) {
+	h.ClientManager.UpdateClientConnStateById(userId, connected)
+	h.recordConnection(userId, connected, h.Clock.Now())
+	if !connected {
+		h.recheckReady()
+	}
+}
+
+// buildRoundResult ranks the players of the round by score, players with the
+// same score share a rank.
+func (h *Hub) buildRoundResult(reason string, now time.Time) *models.RoundResult {
+	h.statsMu.Lock()
+	players := make(map[string]*models.PlayerResult)
+	for userId, stats := range h.stats {
+		connected := stats.connected
+		if !stats.connectedAt.IsZero() {
+			connected += now.Sub(stats.connectedAt)
+		}
+		items := make(map[string]int, len(stats.items))
+		for itemType, count := range stats.items {
+			items[itemType] = count
+		}
+		players[userId] = &models.PlayerResult{
+			ID:               userId,
+			ItemsCollected:   items,
+			Moves:            stats.moves,
+			InvalidMoves:     stats.invalidMoves,
+			ConnectedSeconds: connected.Seconds(),
+		}
+	}
+	h.statsMu.Unlock()
+
+	h.Scores.Range(func(key, value interface{}) bool {
+		userId := key.(string)
+		player, ok := players[userId]
+		if !ok {
+			player = &models.PlayerResult{ID: userId, ItemsCollected: make(map[string]int)}
+			players[userId] = player
+		}
+		player.Score = value.(int)
+		return true
+	})
+	for userId, player := range players {
+		player.Team = h.teamOf(userId)
+	}
+
+	rankings := make([]*models.PlayerResult, 0, len(players))
+	for _, player := range players {
+		rankings = append(rankings, player)
+	}
+	winners := h.breakTie(h.Mode.Winners(h))
+	isWinner := make(map[string]bool, len(winners))
+	for _, userId := range winners {
+		isWinner[userId] = true
+	}
+
+	// winners of a broken tie rank above the players they were tied with
+	sort.Slice(rankings, func(i, j int) bool {
+		if rankings[i].Score != rankings[j].Score {
+			return rankings[i].Score > rankings[j].Score
+		}
+		if isWinner[rankings[i].ID] != isWinner[rankings[j].ID] {
+			return isWinner[rankings[i].ID]
+		}
+		return rankings[i].ID < rankings[j].ID
+	})
+	for i, player := range rankings {
+		player.Rank = i + 1
+		previous := rankings[max(i-1, 0)]
+		if i > 0 && player.Score == previous.Score && isWinner[player.ID] == isWinner[previous.ID] {
+			player.Rank = previous.Rank
+		}
+	}
+
+	return &models.RoundResult{
+		HubID:    h.ID,
+		Mode:     h.Mode.Name(),
+		Reason:   reason,
+		EndedAt:  now,
+		Winners:  winners,
+		Rankings: rankings,
+		Teams:    h.teamResults(),
+	}
+}
+
+// LastRoundResult returns the result of the latest finished round, or nil
+// before the first round has ended.
+func (h *Hub) LastRoundResult() *models.RoundResult {
+	h.statsMu.Lock()
+	defer h.statsMu.Unlock()
+	return h.lastResult
+}
+
+// OnRoundResult registers a function called with every round result.
+func (h *Hub) OnRoundResult(listener func(result *models.RoundResult)) {
+	h.statsMu.Lock()
+	defer h.statsMu.Unlock()
+	h.resultHooks = append(h.resultHooks, listener)
+}
+
+func (h *Hub) publishRoundResult(result *models.RoundResult) {
+	h.statsMu.Lock()
+	h.lastResult = result
+	h.statsMu.Unlock()
+
+	h.ClientManager.BroadcastAll(&models.GameMsg{
+		Type:    models.RoundResultType,
+		Content: result,
+	})
+}
+
+// notifyRoundResult calls the listeners on a copy of their list, so one of
+// them may register another. The round must not be locked, listeners may
+// read it.
+func (h *Hub) notifyRoundResult(result *models.RoundResult) {
+	h.statsMu.Lock()
+	listeners := slices.Clone(h.resultHooks)
+	h.statsMu.Unlock()
+
+	for _, listener := range listeners {
+		listener(result)
+	}
+}
+
+// SendRoundResultToClient sends the last result to a client joining between
+// two rounds, so it sees the same winners as everyone else.
+func (h *Hub) SendRoundResultToClient(client *Client) {
+	h.CurrentRound.Mu.RLock()
+	state := h.CurrentRound.State
+	h.CurrentRound.Mu.RUnlock()
+
+	result := h.LastRoundResult()
+	if result == nil || (state != "ended" && state != "waiting") {
+		return
+	}
+	client.Send <- &models.GameMsg{
+		Type:    models.RoundResultType,
+		Content: result,
+	}
+}
@@ -0,0 +1,36 @@
+package game
+
+import (
+	"testing"
+	"time"
+
+	"pickup/pkg/models"
+)
+
+func TestRoundResultListenersRunAfterTheRoundIsUnlocked(t *testing.T) {
+	h, _ := newTestHub(t, RoomConfig{ID: "stats", Mode: "classic"}, "a")
+	h.setRoundState("playing")
+
+	seen := make(chan string, 1)
+	h.OnRoundResult(func(result *models.RoundResult) {
+		// reads the round, this blocks if the listener runs under its lock
+		seen <- h.roundState()
+	})
+
+	done := make(chan struct{})
+	go func() {
+		h.EndGameRound()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("EndGameRound blocked on a listener reading the round")
+	}
+	if state := <-seen; state != "ended" {
+		t.Fatalf("listener saw the round %q, want ended", state)
+	}
+	if h.LastRoundResult() == nil {
+		t.Fatal("no round result kept after the round ended")
+	}
+}
@@ -0,0 +1,222 @@
+package game
+
+import (
+	"fmt"
+	"go.uber.org/zap"
+	"pickup/pkg/models"
+	"slices"
+	"sort"
+)
+
+// how the players of rooms with TEAMS are put on teams
+const (
+	TeamAssignAuto  = "auto"  // balanced by the server every round
+	TeamAssignLobby = "lobby" // players pick a team with chooseTeam, the rest is balanced
+)
+
+// team names in the order rooms use them, TEAMS picks how many
+var teamNames = []string{"red", "blue", "green", "yellow"}
+
+// hasTeams reports whether the room plays in teams.
+func (h *Hub) hasTeams() bool {
+	return h.Config.Teams > 1
+}
+
+func (h *Hub) teamNames() []string {
+	return teamNames[:h.Config.Teams]
+}
+
+// teamOf returns the team of a player, empty outside team rooms.
+func (h *Hub) teamOf(userId string) string {
+	h.teamsMu.Lock()
+	defer h.teamsMu.Unlock()
+	return h.teams[userId]
+}
+
+// sameTeam reports whether two players play on the same team.
+func (h *Hub) sameTeam(userId, otherId string) bool {
+	team := h.teamOf(userId)
+	return team != "" && team == h.teamOf(otherId)
+}
+
+// passesThrough reports whether a player may share the cell of another,
+// teammates can in rooms with TEAM_PASS_THROUGH.
+func (h *Hub) passesThrough(userId, otherId string) bool {
+	return h.Config.TeamPassThrough && h.sameTeam(userId, otherId)
+}
+
+// ChooseTeam records the team a player wants to play on from the next
+// round, in rooms where the players pick their teams.
+func (h *Hub) ChooseTeam(userId, team string) error {
+	if !h.hasTeams() || h.Config.TeamAssignment != TeamAssignLobby {
+		return fmt.Errorf("teams of hub %s are not picked by the players", h.ID)
+	}
+	if !slices.Contains(h.teamNames(), team) {
+		return fmt.Errorf("hub %s has no team %s", h.ID, team)
+	}
+	if h.isRoundRunning() {
+		return fmt.Errorf("user %s can't change teams during a round", userId)
+	}
+
+	h.teamsMu.Lock()
+	h.teamChoices[userId] = team
+	state := h.teamState(h.teamChoices)
+	h.teamsMu.Unlock()
+
+	zap.S().Debugf("hub: %v user %v picked team %v", h.ID, userId, team)
+	h.ClientManager.BroadcastAll(&models.GameMsg{Type: models.TeamStateType, Content: state})
+	return nil
+}
+
+// assignTeams puts the players of the round being prepared on teams. Picks
+// are kept as long as no team gets more than its share, everyone else goes
+// to the smallest team.
+func (h *Hub) assignTeams(players []*Client) {
+	if !h.hasTeams() {
+		return
+	}
+	names := h.teamNames()
+	share := (len(players) + len(names) - 1) / len(names)
+	sizes := make(map[string]int, len(names))
+
+	h.teamsMu.Lock()
+	defer h.teamsMu.Unlock()
+	h.teams = make(map[string]string, len(players))
+
+	unassigned := make([]*Client, 0, len(players))
+	for _, client := range players {
+		team := h.teamChoices[client.ID]
+		if h.Config.TeamAssignment == TeamAssignLobby && team != "" && sizes[team] < share {
+			h.teams[client.ID] = team
+			sizes[team]++
+			continue
+		}
+		unassigned = append(unassigned, client)
+	}
+
+	h.rng.Shuffle(len(unassigned), func(i, j int) { unassigned[i], unassigned[j] = unassigned[j], unassigned[i] })
+	for _, client := range unassigned {
+		smallest := names[0]
+		for _, team := range names[1:] {
+			if sizes[team] < sizes[smallest] {
+				smallest = team
+			}
+		}
+		h.teams[client.ID] = smallest
+		sizes[smallest]++
+	}
+
+	for _, team := range names {
+		h.TeamScores.Store(team, 0)
+	}
+	zap.S().Infof("hub: %v teams %v", h.ID, h.teams)
+}
+
+// addTeamScore adds a change of a player's score to their team.
+func (h *Hub) addTeamScore(userId string, value int) {
+	team := h.teamOf(userId)
+	if team == "" || value == 0 {
+		return
+	}
+	score, _ := h.TeamScores.LoadOrStore(team, 0)
+	h.TeamScores.Store(team, score.(int)+value)
+}
+
+func (h *Hub) teamScore(team string) int {
+	score, _ := h.TeamScores.Load(team)
+	if score == nil {
+		return 0
+	}
+	return score.(int)
+}
+
+// scoreUpdate builds the score message of a player, with the total of
+// their team in team rooms.
+func (h *Hub) scoreUpdate(userId string, score int) *models.ScoreUpdate {
+	update := &models.ScoreUpdate{ID: userId, Score: score}
+	if team := h.teamOf(userId); team != "" {
+		update.Team = team
+		update.TeamScore = h.teamScore(team)
+	}
+	return update
+}
+
+// teamState lists the members of every team, it expects teamsMu to be held.
+func (h *Hub) teamState(members map[string]string) *models.TeamState {
+	state := &models.TeamState{Assignment: h.Config.TeamAssignment, Teams: make(map[string][]string)}
+	for _, team := range h.teamNames() {
+		state.Teams[team] = make([]string, 0)
+	}
+	for userId, team := range members {
+		state.Teams[team] = append(state.Teams[team], userId)
+	}
+	for _, team := range state.Teams {
+		sort.Strings(team)
+	}
+	return state
+}
+
+func (h *Hub) SendTeamStateToClient(client *Client) {
+	if !h.hasTeams() {
+		return
+	}
+	h.teamsMu.Lock()
+	members := h.teams
+	if len(members) == 0 {
+		members = h.teamChoices
+	}
+	state := h.teamState(members)
+	h.teamsMu.Unlock()
+
+	client.Send <- &models.GameMsg{Type: models.TeamStateType, Content: state}
+}
+
+// topTeams returns the members of the teams sharing the highest score,
+// ignoring rounds where no team scored.
+func (h *Hub) topTeams() []string {
+	best := 0
+	top := make([]string, 0)
+	for _, team := range h.teamNames() {
+		switch score := h.teamScore(team); {
+		case score > best:
+			best = score
+			top = []string{team}
+		case score == best && score > 0:
+			top = append(top, team)
+		}
+	}
+
+	h.teamsMu.Lock()
+	defer h.teamsMu.Unlock()
+	winners := make([]string, 0)
+	for userId, team := range h.teams {
+		if slices.Contains(top, team) {
+			winners = append(winners, userId)
+		}
+	}
+	sort.Strings(winners)
+	return winners
+}
+
+// teamResults ranks the teams of the round by their score.
+func (h *Hub) teamResults() []*models.TeamResult {
+	if !h.hasTeams() {
+		return nil
+	}
+	h.teamsMu.Lock()
+	state := h.teamState(h.teams)
+	h.teamsMu.Unlock()
+
+	results := make([]*models.TeamResult, 0, len(state.Teams))
+	for _, team := range h.teamNames() {
+		results = append(results, &models.TeamResult{Team: team, Score: h.teamScore(team), Members: state.Teams[team]})
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	for i, result := range results {
+		result.Rank = i + 1
+		if i > 0 && result.Score == results[i-1].Score {
+			result.Rank = results[i-1].Rank
+		}
+	}
+	return results
+}
@@ -0,0 +1,161 @@
+package game
+
+import (
+	"go.uber.org/zap"
+	"pickup/internal/global"
+	"pickup/pkg/models"
+	"sort"
+	"time"
+)
+
+// playerInput is a single queued move or action of a player.
+type playerInput struct {
+	position *models.PlayerPosition
+	action   *models.ItemAction
+	attack   *models.Attack
+}
+
+// outboxMsg is an event waiting for the end of the tick, an empty userId
+// addresses every client.
+type outboxMsg struct {
+	userId string
+	msg    *models.GameMsg
+}
+
+func (h *Hub) tickInterval() time.Duration {
+	rate := global.Dv.GetInt("TICK_RATE")
+	if rate <= 0 {
+		rate = 20
+	}
+	return time.Second / time.Duration(rate)
+}
+
+// queueInput stores an input until the next tick, inputs beyond the queue
+// limit are dropped so a flooding client can't build up a backlog.
+func (h *Hub) queueInput(userId string, input *playerInput) {
+	queue := h.inputs[userId]
+	if max := global.Dv.GetInt("TICK_MAX_QUEUED_INPUTS"); max > 0 && len(queue) >= max {
+		zap.S().Debugf("hub: %v input queue of user %v is full, input dropped", h.ID, userId)
+		return
+	}
+	h.inputs[userId] = append(queue, input)
+}
+
+// inputOrder returns the players with queued inputs in the order they are
+// applied this tick. Players are sorted by id and the starting player
+// rotates every tick, so no one always wins a contested cell.
+func (h *Hub) inputOrder() []string {
+	order := make([]string, 0, len(h.inputs))
+	for userId := range h.inputs {
+		order = append(order, userId)
+	}
+	if len(order) == 0 {
+		return order
+	}
+	sort.Strings(order)
+	shift := int(h.tickCount % uint64(len(order)))
+	return append(order[shift:], order[:shift]...)
+}
+
+// step runs one simulation tick: one queued input per player, the mode
+// timers, the end conditions and finally one state update per client.
+func (h *Hub) step() {
+	h.tickCount++
+
+	for _, userId := range h.inputOrder() {
+		input := h.inputs[userId][0]
+		if len(h.inputs[userId]) == 1 {
+			delete(h.inputs, userId)
+		} else {
+			h.inputs[userId] = h.inputs[userId][1:]
+		}
+		h.applyInput(input)
+	}
+
+	if h.isRoundRunning() {
+		h.expireItems(h.Clock.Now())
+		h.expireEffects(h.Clock.Now())
+		h.tickCurses(h.Clock.Now())
+		h.tickEntities(h.Clock.Now())
+	} else {
+		h.resetRoundTimers()
+	}
+	h.tickGameMode()
+	h.startOvertime()
+	h.checkRoundEnd()
+	h.updateViews()
+	h.flushOutbox()
+}
+
+// resetRoundTimers makes the timers of the round start over when the next
+// one plays.
+func (h *Hub) resetRoundTimers() {
+	h.nextItemSpawn = time.Time{}
+	h.nextCurseDrain = time.Time{}
+	h.nextEntityMove = time.Time{}
+}
+
+func (h *Hub) applyInput(input *playerInput) {
+	switch {
+	case input.position != nil:
+		if err := h.handlePositionUpdate(input.position); err != nil {
+			zap.S().Errorf("failed handling PlayerPotition due to: %s", err.Error())
+		}
+	case input.action != nil:
+		if err := h.handleItemAction(input.action); err != nil {
+			zap.S().Errorf("failed handling ItemAction due to: %s", err.Error())
+		}
+	case input.attack != nil:
+		if err := h.handleAttack(input.attack); err != nil {
+			zap.S().Errorf("failed handling Attack due to: %s", err.Error())
+		}
+	}
+}
+
+// broadcast queues an event for every client until the end of the tick.
+func (h *Hub) broadcast(msg *models.GameMsg) {
+	h.outboxMu.Lock()
+	defer h.outboxMu.Unlock()
+	h.outbox = append(h.outbox, &outboxMsg{msg: msg})
+}
+
+// sendToClient queues an event for a single client until the end of the tick.
+func (h *Hub) sendToClient(userId string, msg *models.GameMsg) {
+	h.outboxMu.Lock()
+	defer h.outboxMu.Unlock()
+	h.outbox = append(h.outbox, &outboxMsg{userId: userId, msg: msg})
+}
+
+// flushOutbox sends the events of the tick as one stateUpdate per client,
+// keeping the order in which they happened.
+func (h *Hub) flushOutbox() {
+	h.outboxMu.Lock()
+	outbox := h.outbox
+	h.outbox = nil
+	h.outboxMu.Unlock()
+
+	if len(outbox) == 0 {
+		return
+	}
+
+	sights := h.sights()
+	h.ClientManager.SendEach(func(client *Client) *models.GameMsg {
+		s := sights[client.ID]
+		events := make([]*models.GameMsg, 0, len(outbox))
+		for _, queued := range outbox {
+			if (queued.userId == "" || queued.userId == client.ID) && h.visibleTo(client.ID, s, queued.msg) {
+				events = append(events, queued.msg)
+			}
+		}
+		if len(events) == 0 {
+			return nil
+		}
+		return &models.GameMsg{
+			Type: models.StateUpdateType,
+			Content: &models.StateUpdate{
+				Tick:   h.tickCount,
+				Events: events,
+			},
+		}
+	})
+}
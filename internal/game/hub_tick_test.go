@@ -0,0 +1,164 @@
+package game
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"pickup/pkg/models"
+)
+
+func moveInput(id string, x, y int) *playerInput {
+	return &playerInput{position: &models.PlayerPosition{ID: id, Position: &models.Position{X: x, Y: y}}}
+}
+
+func TestStepAppliesOneInputPerPlayerPerTick(t *testing.T) {
+	h, _ := newTestHub(t, RoomConfig{ID: "tick", Mode: "classic"})
+	h.setRoundState("playing")
+	placePlayer(h, "a", 0, 0)
+
+	h.queueInput("a", moveInput("a", 1, 0))
+	h.queueInput("a", moveInput("a", 2, 0))
+
+	h.step()
+	if got := playerPosition(h, "a"); got != (models.Position{X: 1, Y: 0}) {
+		t.Fatalf("after one tick a is at %v, want (1, 0)", got)
+	}
+	h.step()
+	if got := playerPosition(h, "a"); got != (models.Position{X: 2, Y: 0}) {
+		t.Fatalf("after two ticks a is at %v, want (2, 0)", got)
+	}
+	if len(h.inputs) != 0 {
+		t.Fatalf("inputs left after both were applied: %v", h.inputs)
+	}
+}
+
+func TestQueueInputDropsInputsBeyondTheLimit(t *testing.T) {
+	setConfig(t, "TICK_MAX_QUEUED_INPUTS", 2)
+	h, _ := newTestHub(t, RoomConfig{ID: "tick", Mode: "classic"})
+
+	for x := 1; x <= 3; x++ {
+		h.queueInput("a", moveInput("a", x, 0))
+	}
+	if got := len(h.inputs["a"]); got != 2 {
+		t.Fatalf("queued %d inputs, want 2", got)
+	}
+}
+
+func TestInputOrderRotatesEveryTick(t *testing.T) {
+	h, _ := newTestHub(t, RoomConfig{ID: "tick", Mode: "classic"})
+	for _, id := range []string{"c", "a", "b"} {
+		h.queueInput(id, moveInput(id, 0, 0))
+	}
+
+	for tick, want := range [][]string{{"a", "b", "c"}, {"b", "c", "a"}, {"c", "a", "b"}} {
+		h.tickCount = uint64(tick)
+		if got := h.inputOrder(); !slices.Equal(got, want) {
+			t.Fatalf("order at tick %d is %v, want %v", tick, got, want)
+		}
+	}
+}
+
+func TestContestedCellGoesToThePlayerFirstInOrder(t *testing.T) {
+	h, _ := newTestHub(t, RoomConfig{ID: "tick", Mode: "classic"})
+	h.setRoundState("playing")
+	placePlayer(h, "a", 0, 1)
+	placePlayer(h, "b", 2, 1)
+
+	// the step makes it tick 2, which starts with a
+	h.tickCount = 1
+	h.queueInput("a", moveInput("a", 1, 1))
+	h.queueInput("b", moveInput("b", 1, 1))
+	h.step()
+
+	if got := playerPosition(h, "a"); got != (models.Position{X: 1, Y: 1}) {
+		t.Fatalf("a is at %v, want the contested cell", got)
+	}
+	if got := playerPosition(h, "b"); got != (models.Position{X: 2, Y: 1}) {
+		t.Fatalf("b moved to %v, want it to stay", got)
+	}
+}
+
+func TestFlushOutboxSendsOneStateUpdatePerClient(t *testing.T) {
+	h, _ := newTestHub(t, RoomConfig{ID: "tick", Mode: "classic"})
+	a, b := addClient(h, "a"), addClient(h, "b")
+
+	h.broadcast(&models.GameMsg{Type: models.ErrorType, Content: &models.Error{ID: "a", Error: "first"}})
+	h.sendToClient("a", &models.GameMsg{Type: models.AlertType, Content: &models.Alert{ID: "a", Text: "only a"}})
+	h.broadcast(&models.GameMsg{Type: models.ErrorType, Content: &models.Error{ID: "b", Error: "second"}})
+	h.flushOutbox()
+
+	for client, want := range map[*Client][]models.GameMsgType{
+		a: {models.ErrorType, models.AlertType, models.ErrorType},
+		b: {models.ErrorType, models.ErrorType},
+	} {
+		if len(client.Send) != 1 {
+			t.Fatalf("%s got %d messages, want one state update", client.ID, len(client.Send))
+		}
+		update := (<-client.Send).Content.(*models.StateUpdate)
+		got := make([]models.GameMsgType, 0, len(update.Events))
+		for _, event := range update.Events {
+			got = append(got, event.Type)
+		}
+		if !slices.Equal(got, want) {
+			t.Fatalf("%s got events %v, want %v", client.ID, got, want)
+		}
+	}
+
+	h.flushOutbox()
+	if len(a.Send)+len(b.Send) != 0 {
+		t.Fatal("an empty outbox was sent")
+	}
+}
+
+func TestFlushOutboxDropsAClientWithAFullBuffer(t *testing.T) {
+	h, _ := newTestHub(t, RoomConfig{ID: "tick", Mode: "classic"})
+	slow, fast := addClient(h, "slow"), addClient(h, "fast")
+	for len(slow.Send) < cap(slow.Send) {
+		slow.Send <- &models.GameMsg{Type: models.ErrorType}
+	}
+
+	h.broadcast(&models.GameMsg{Type: models.ErrorType, Content: &models.Error{ID: "a", Error: "lost"}})
+	h.flushOutbox()
+
+	select {
+	case <-slow.Done:
+	default:
+		t.Fatal("a client that missed an update kept its connection")
+	}
+	select {
+	case <-fast.Done:
+		t.Fatal("a client with room in its buffer was dropped")
+	default:
+	}
+	if len(fast.Send) != 1 {
+		t.Fatalf("fast client got %d messages, want 1", len(fast.Send))
+	}
+
+	// a second miss doesn't close the connection twice
+	h.broadcast(&models.GameMsg{Type: models.ErrorType, Content: &models.Error{ID: "a", Error: "lost"}})
+	h.flushOutbox()
+}
+
+func TestRoundTimersStartOverBetweenRounds(t *testing.T) {
+	setConfig(t, "ITEM_SPAWN_INTERVAL_SEC", 5)
+	h, clock := newTestHub(t, RoomConfig{ID: "tick", Mode: "classic"})
+	h.setRoundState("playing")
+	h.step()
+	if h.nextItemSpawn.IsZero() {
+		t.Fatal("item spawner did not start while playing")
+	}
+
+	h.setRoundState("ended")
+	h.step()
+	if !h.nextItemSpawn.IsZero() || !h.nextCurseDrain.IsZero() || !h.nextEntityMove.IsZero() {
+		t.Fatal("round timers kept running after the round")
+	}
+
+	clock.Advance(time.Minute)
+	h.setRoundState("playing")
+	h.step()
+	if want := clock.Now().Add(5 * time.Second); !h.nextItemSpawn.Equal(want) {
+		t.Fatalf("next spawn at %v, want a full interval after the new round started", h.nextItemSpawn)
+	}
+}
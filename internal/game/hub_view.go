@@ -0,0 +1,248 @@
+package game
+
+import "pickup/pkg/models"
+
+// hasFog reports whether the room hides part of the board from its players.
+func (h *Hub) hasFog() bool {
+	return h.Config.VisibilityRadius > 0 || h.Config.LineOfSight
+}
+
+// sight is what a player on the board can see, a nil sight sees everything.
+type sight struct {
+	origin  models.Position
+	radius  int                      // 0 doesn't limit the distance
+	blocked map[models.Position]bool // obstacles hiding what is behind them, nil without LINE_OF_SIGHT
+}
+
+// sightOf returns the sight of a player, players off the board and
+// spectators see the whole board. Sending to every player should build
+// their sights once with sights instead.
+func (h *Hub) sightOf(userId string) *sight {
+	if !h.hasFog() {
+		return nil
+	}
+	position, ok := h.UsersInMap.Load(userId)
+	if !ok {
+		return nil
+	}
+	return &sight{origin: *position.(*models.Position), radius: h.Config.VisibilityRadius, blocked: h.blockedCells()}
+}
+
+// sights returns the sight of every player on the board, they share one
+// map of the obstacles.
+func (h *Hub) sights() map[string]*sight {
+	sights := make(map[string]*sight)
+	if !h.hasFog() {
+		return sights
+	}
+	blocked := h.blockedCells()
+	h.UsersInMap.Range(func(key, value interface{}) bool {
+		sights[key.(string)] = &sight{origin: *value.(*models.Position), radius: h.Config.VisibilityRadius, blocked: blocked}
+		return true
+	})
+	return sights
+}
+
+// blockedCells returns the cells that hide what is behind them, nil without
+// LINE_OF_SIGHT.
+func (h *Hub) blockedCells() map[models.Position]bool {
+	if !h.Config.LineOfSight {
+		return nil
+	}
+	blocked := make(map[models.Position]bool)
+	for _, obstacle := range h.GetObstacles() {
+		blocked[*obstacle.Position] = true
+	}
+	return blocked
+}
+
+func (s *sight) sees(cell models.Position) bool {
+	if s == nil {
+		return true
+	}
+	dx, dy := cell.X-s.origin.X, cell.Y-s.origin.Y
+	if s.radius > 0 && dx*dx+dy*dy > s.radius*s.radius {
+		return false
+	}
+	for _, between := range lineCells(s.origin, cell) {
+		if s.blocked[between] {
+			return false
+		}
+	}
+	return true
+}
+
+// canSee is the view filter of the hub's ClientManager: events on a cell
+// only reach the clients that see the cell, or the player they are about.
+func (h *Hub) canSee(client *Client, msg *models.GameMsg) bool {
+	// most messages aren't tied to a cell, they skip building the sight
+	if _, ok := msg.Content.(*models.Explosion); !ok {
+		if cell, _ := eventCell(msg); cell == nil {
+			return true
+		}
+	}
+	return h.visibleTo(client.ID, h.sightOf(client.ID), msg)
+}
+
+// visibleTo reports whether a player with the given sight may get an event.
+func (h *Hub) visibleTo(userId string, s *sight, msg *models.GameMsg) bool {
+	// a blast is seen from any of its cells
+	if explosion, ok := msg.Content.(*models.Explosion); ok {
+		for _, cell := range explosion.Cells {
+			if s.sees(*cell) {
+				return true
+			}
+		}
+		return false
+	}
+	cell, owner := eventCell(msg)
+	if cell == nil || owner == userId || owner != "" && h.sameTeam(owner, userId) {
+		return true
+	}
+	if cell == unplaced {
+		return s == nil
+	}
+	return s.sees(*cell)
+}
+
+// playerVisibleTo reports whether a player with the given sight sees another
+// player, teammates always see each other.
+func (h *Hub) playerVisibleTo(userId string, s *sight, otherId string) bool {
+	if otherId == userId || h.sameTeam(otherId, userId) {
+		return true
+	}
+	position, ok := h.UsersInMap.Load(otherId)
+	return ok && s.sees(*position.(*models.Position))
+}
+
+// eventCell returns the cell an event happens on and the player it is
+// about, nil for events that aren't tied to a cell. Events on a cell that
+// aren't listed here are filtered by their cell alone.
+func eventCell(msg *models.GameMsg) (*models.Position, string) {
+	switch content := msg.Content.(type) {
+	case *models.PlayerPosition:
+		return content.Position, content.ID
+	case *models.ItemAction:
+		return content.Position, content.ID
+	case *models.Bomb:
+		return content.Position, content.ID
+	case *models.ObstacleHit:
+		return content.Position, content.By
+	case *models.Flag:
+		// what happens to a flag is public, where its carrier takes it is not
+		if content.Event != FlagCarried {
+			return nil, ""
+		}
+		return content.Position, content.Carrier
+	case *models.ViewChange:
+		// sent to a single player about its own view
+		return nil, ""
+	case interface{ Cell() *models.Position }:
+		if cell := content.Cell(); cell != nil {
+			return cell, ""
+		}
+		return unplaced, ""
+	}
+	return nil, ""
+}
+
+// unplaced is the cell of events that should have one but don't, only
+// players seeing the whole board get them.
+var unplaced = &models.Position{X: -1, Y: -1}
+
+// viewObjects returns the players, entities and items on the board keyed
+// for the view tracking.
+func (h *Hub) viewObjects() map[string]*models.ViewChange {
+	objects := make(map[string]*models.ViewChange)
+	h.UsersInMap.Range(func(key, value interface{}) bool {
+		position := value.(*models.Position)
+		objects["player:"+key.(string)] = &models.ViewChange{
+			Kind:     "player",
+			ID:       key.(string),
+			Position: position,
+			State: &models.GameMsg{
+				Type:    models.PlayerPositionType,
+				Content: &models.PlayerPosition{Valid: true, ID: key.(string), Position: position},
+			},
+		}
+		return true
+	})
+	h.ItemsInMap.Range(func(key, value interface{}) bool {
+		itemAction := value.(*models.ItemAction)
+		objects["item:"+key.(string)] = &models.ViewChange{
+			Kind:     "item",
+			ID:       key.(string),
+			Position: itemAction.Position,
+			State:    &models.GameMsg{Type: "itemPosition", Content: itemAction},
+		}
+		return true
+	})
+	h.entitiesMu.Lock()
+	for id, entity := range h.entities {
+		objects["entity:"+id] = &models.ViewChange{
+			Kind:     "entity",
+			ID:       id,
+			Position: entity.Position,
+			State: &models.GameMsg{
+				Type:    models.EntityPositionType,
+				Content: &models.Entity{ID: id, Kind: entity.Kind, Position: entity.Position},
+			},
+		}
+	}
+	h.entitiesMu.Unlock()
+	return objects
+}
+
+// updateViews tells every player what came into and went out of their view
+// during the tick, players only get the state of what they can see.
+func (h *Hub) updateViews() {
+	if !h.hasFog() {
+		return
+	}
+	objects := h.viewObjects()
+	sights := h.sights()
+
+	h.viewsMu.Lock()
+	defer h.viewsMu.Unlock()
+	for _, client := range h.ClientManager.SortedClients() {
+		s := sights[client.ID]
+		seen := h.views[client.ID]
+		visible := make(map[string]*models.ViewChange)
+		for key, object := range objects {
+			if object.Kind == "player" && object.ID == client.ID {
+				continue
+			}
+			// teammates always see each other
+			if !s.sees(*object.Position) && !(object.Kind == "player" && h.sameTeam(object.ID, client.ID)) {
+				continue
+			}
+			visible[key] = object
+			if seen[key] == nil {
+				h.sendToClient(client.ID, &models.GameMsg{Type: models.EnterViewType, Content: object})
+			}
+		}
+		for key, object := range seen {
+			if visible[key] == nil {
+				h.sendToClient(client.ID, &models.GameMsg{
+					Type:    models.LeaveViewType,
+					Content: &models.ViewChange{Kind: object.Kind, ID: object.ID, Position: object.Position},
+				})
+			}
+		}
+		h.views[client.ID] = visible
+	}
+}
+
+// resetView makes the next tick resend everything a player can see, after
+// the state sync of a new round or a reconnect.
+func (h *Hub) resetView(userId string) {
+	h.viewsMu.Lock()
+	defer h.viewsMu.Unlock()
+	delete(h.views, userId)
+}
+
+func (h *Hub) clearViews() {
+	h.viewsMu.Lock()
+	defer h.viewsMu.Unlock()
+	h.views = make(map[string]map[string]*models.ViewChange)
+}
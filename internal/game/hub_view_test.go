@@ -0,0 +1,88 @@
+package game
+
+import (
+	"fmt"
+	"testing"
+
+	"pickup/pkg/models"
+)
+
+// flushedTypes flushes the outbox and returns the event types a client got.
+func flushedTypes(h *Hub, client *Client) []models.GameMsgType {
+	h.flushOutbox()
+	types := make([]models.GameMsgType, 0)
+	for len(client.Send) > 0 {
+		msg := <-client.Send
+		if update, ok := msg.Content.(*models.StateUpdate); ok {
+			for _, event := range update.Events {
+				types = append(types, event.Type)
+			}
+		}
+	}
+	return types
+}
+
+func TestFogHidesEveryEventOnACellOutOfSight(t *testing.T) {
+	h, _ := newTestHub(t, RoomConfig{ID: "fog", Mode: "classic", VisibilityRadius: 3})
+	a, b := addClient(h, "a"), addClient(h, "b")
+	placePlayer(h, "a", 0, 0)
+	placePlayer(h, "b", 10, 10)
+
+	far := &models.Position{X: 10, Y: 9}
+	for _, msg := range []*models.GameMsg{
+		{Type: models.ObstacleHitType, Content: &models.ObstacleHit{By: "b", Obstacle: &models.Obstacle{Position: far, Type: "crate", HP: 2, MaxHP: 3}}},
+		{Type: models.FlagStateType, Content: &models.Flag{Team: "red", Event: FlagCarried, Carrier: "b", Position: far}},
+		{Type: models.EntityPositionType, Content: &models.Entity{ID: "guard-1", Kind: EntityGuard, Position: far}},
+		{Type: models.BombExplodedType, Content: &models.Explosion{ID: "bomb", Cells: []*models.Position{far}, Position: far}},
+	} {
+		h.broadcast(msg)
+		if got := flushedTypes(h, a); len(got) != 0 {
+			t.Fatalf("a far away got %v", got)
+		}
+		if got := flushedTypes(h, b); len(got) != 1 {
+			t.Fatalf("b next to the cell got %v, want the %s", got, msg.Type)
+		}
+	}
+}
+
+func TestFogShowsFlagsOnTheBoardAndBlastsReachingASight(t *testing.T) {
+	h, _ := newTestHub(t, RoomConfig{ID: "fog", Mode: "classic", VisibilityRadius: 3})
+	a := addClient(h, "a")
+	placePlayer(h, "a", 0, 0)
+
+	far := &models.Position{X: 10, Y: 10}
+	h.broadcast(&models.GameMsg{Type: models.FlagStateType, Content: &models.Flag{Team: "red", Event: "returned", Position: far}})
+	h.broadcast(&models.GameMsg{Type: models.BombExplodedType, Content: &models.Explosion{
+		ID:       "bomb",
+		Cells:    []*models.Position{{X: 3, Y: 0}, {X: 4, Y: 0}},
+		Position: &models.Position{X: 4, Y: 0},
+	}})
+	if got := flushedTypes(h, a); len(got) != 2 {
+		t.Fatalf("a got %v, want the flag going home and the blast", got)
+	}
+}
+
+func TestStateSyncOnlySendsWhatThePlayerSees(t *testing.T) {
+	h, _ := newTestHub(t, RoomConfig{ID: "fog", Mode: "classic", VisibilityRadius: 5, LineOfSight: true})
+	a := addClient(h, "a")
+	placePlayer(h, "a", 0, 0)
+	putObstacle(h, "rock", 2, 0)
+	h.ItemsInMap.Range(func(key, _ interface{}) bool {
+		h.ItemsInMap.Delete(key)
+		return true
+	})
+	for _, cell := range []models.Position{{X: 1, Y: 0}, {X: 3, Y: 0}, {X: 0, Y: 9}} {
+		h.ItemsInMap.Store(fmt.Sprintf("%d-%d", cell.X, cell.Y), &models.ItemAction{Position: &models.Position{X: cell.X, Y: cell.Y}})
+	}
+
+	h.SendAllItemToClient(a)
+	if len(a.Send) != 1 {
+		t.Fatalf("a got %d items, want only the one in front of the rock", len(a.Send))
+	}
+	if item := (<-a.Send).Content.(*models.ItemAction); item.Position.X != 1 {
+		t.Fatalf("a got the item at %+v", item.Position)
+	}
+	if !h.canSee(a, &models.GameMsg{Type: models.AlertType, Content: &models.Alert{ID: "b", Text: "hi"}}) {
+		t.Fatal("a message without a cell was hidden")
+	}
+}
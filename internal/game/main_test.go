@@ -0,0 +1,93 @@
+package game
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/spf13/viper"
+	"pickup/internal/global"
+	"pickup/pkg/models"
+)
+
+// TestMain loads the dev config, the item catalogue, the obstacle types and
+// the maps the way the server does, from the repository root.
+func TestMain(m *testing.M) {
+	if err := os.Chdir("../.."); err != nil {
+		panic(err)
+	}
+	global.Dv = viper.New()
+	global.Dv.SetConfigFile("config.yaml")
+	if err := global.Dv.ReadInConfig(); err != nil {
+		panic(err)
+	}
+	global.Dv = global.Dv.Sub("dev")
+
+	var err error
+	if Catalogue, err = LoadItemCatalogue(); err != nil {
+		panic(err)
+	}
+	if ObstacleTypes, err = LoadObstacleTypes(); err != nil {
+		panic(err)
+	}
+	if Maps, err = LoadMaps(global.Dv.GetString("MAP_DIR")); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// setConfig overrides a config key for the rest of the test.
+func setConfig(t *testing.T, key string, value interface{}) {
+	t.Helper()
+	previous := global.Dv.Get(key)
+	global.Dv.Set(key, value)
+	t.Cleanup(func() { global.Dv.Set(key, previous) })
+}
+
+// newTestHub creates a hub on a fake clock with connected players whose
+// messages are read and dropped.
+func newTestHub(t *testing.T, cfg RoomConfig, players ...string) (*Hub, *fakeClock) {
+	t.Helper()
+	clock := newFakeClock()
+	h, err := NewHubWithClock(nil, cfg, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range players {
+		client := NewClient(id, h, nil)
+		h.ClientManager.RegisterClient(client)
+		go func() {
+			for range client.Send {
+			}
+		}()
+	}
+	return h, clock
+}
+
+// addClient connects a player whose messages are left for the test to read.
+func addClient(h *Hub, id string) *Client {
+	client := NewClient(id, h, nil)
+	h.ClientManager.RegisterClient(client)
+	return client
+}
+
+// placePlayer puts a player on a cell of the board.
+func placePlayer(h *Hub, id string, x, y int) {
+	position := &models.Position{X: x, Y: y}
+	h.UsersInMap.Store(id, position)
+	h.OccupiedInMap.Store(fmt.Sprintf("%d-%d", x, y), position)
+}
+
+func playerPosition(h *Hub, id string) models.Position {
+	position, ok := h.UsersInMap.Load(id)
+	if !ok {
+		return models.Position{X: -1, Y: -1}
+	}
+	return *position.(*models.Position)
+}
+
+func (h *Hub) setRoundState(state string) {
+	h.CurrentRound.Mu.Lock()
+	defer h.CurrentRound.Mu.Unlock()
+	h.CurrentRound.State = state
+}
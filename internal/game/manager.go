@@ -3,7 +3,9 @@ package game
 import (
 	"fmt"
 	"go.uber.org/zap"
+	"pickup/internal/global"
 	"pickup/pkg/models"
+	"sort"
 	"sync"
 	"time"
 )
@@ -22,9 +24,86 @@ func (hm *HubManager) GetHubById(id string) *Hub {
 	return nil
 }
 
-func NewHub(hm *HubManager, id string) *Hub {
+// GetHubs returns every hub ordered by id.
+func (hm *HubManager) GetHubs() []*Hub {
+	hm.Mu.RLock()
+	hubs := make([]*Hub, 0, len(hm.Hubs))
+	for _, hub := range hm.Hubs {
+		hubs = append(hubs, hub)
+	}
+	hm.Mu.RUnlock()
+
+	sort.Slice(hubs, func(i, j int) bool { return hubs[i].ID < hubs[j].ID })
+	return hubs
+}
+
+// RoomConfig describes a room as listed under ROOMS in config.yaml.
+type RoomConfig struct {
+	ID         string   `mapstructure:"ID"`
+	Mode       string   `mapstructure:"MODE"`
+	MinPlayers int      `mapstructure:"MIN_PLAYERS"` // defaults to ROUND_MIN_PLAYERS
+	Lobby      bool     `mapstructure:"LOBBY"`       // ready-check before every round
+	Maps       []string `mapstructure:"MAPS"`        // map rotation, one per round
+	Width      int      `mapstructure:"WIDTH"`       // defaults to GRIDSIZE, maps bring their own
+	Height     int      `mapstructure:"HEIGHT"`      // defaults to GRIDSIZE, maps bring their own
+	ScaleArena bool     `mapstructure:"SCALE_ARENA"` // size the board for the players of each round
+	Guards     int      `mapstructure:"GUARDS"`      // patrolling entities per round
+	Monsters   int      `mapstructure:"MONSTERS"`    // entities chasing the players
+	// fog of war, players only see the cells within VISIBILITY_RADIUS (0 no
+	// limit) and, with LINE_OF_SIGHT, not behind obstacles
+	VisibilityRadius int  `mapstructure:"VISIBILITY_RADIUS"`
+	LineOfSight      bool `mapstructure:"LINE_OF_SIGHT"`
+	// teams, TEAMS (2 to 4) puts the players on teams by TEAM_ASSIGNMENT
+	// (auto or lobby, default auto), TEAM_PASS_THROUGH lets teammates share cells
+	Teams           int    `mapstructure:"TEAMS"`
+	TeamAssignment  string `mapstructure:"TEAM_ASSIGNMENT"`
+	TeamPassThrough bool   `mapstructure:"TEAM_PASS_THROUGH"`
+}
+
+func NewHub(hm *HubManager, cfg RoomConfig) (*Hub, error) {
+	return NewHubWithClock(hm, cfg, systemClock{})
+}
+
+// NewHubWithClock creates a hub whose round timeline is read from clock.
+func NewHubWithClock(hm *HubManager, cfg RoomConfig, clock Clock) (*Hub, error) {
+	mode, err := NewGameMode(cfg.Mode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create hub %s: %w", cfg.ID, err)
+	}
+
+	for _, name := range cfg.Maps {
+		if _, ok := Maps[name]; !ok && name != RandomMap {
+			return nil, fmt.Errorf("failed to create hub %s: unknown map %s", cfg.ID, name)
+		}
+	}
+
+	if cfg.Width <= 0 {
+		cfg.Width = global.Dv.GetInt("GRIDSIZE")
+	}
+	if cfg.Height <= 0 {
+		cfg.Height = global.Dv.GetInt("GRIDSIZE")
+	}
+
+	if _, ok := mode.(teamMode); ok && cfg.Teams < 2 {
+		cfg.Teams = 2
+	}
+	if cfg.Teams > len(teamNames) {
+		return nil, fmt.Errorf("failed to create hub %s: at most %d teams", cfg.ID, len(teamNames))
+	}
+	if cfg.TeamAssignment == "" {
+		cfg.TeamAssignment = TeamAssignAuto
+	}
+	if cfg.TeamAssignment != TeamAssignAuto && cfg.TeamAssignment != TeamAssignLobby {
+		return nil, fmt.Errorf("failed to create hub %s: unknown team assignment %s", cfg.ID, cfg.TeamAssignment)
+	}
+
+	if cfg.MinPlayers <= 0 {
+		cfg.MinPlayers = max(global.Dv.GetInt("ROUND_MIN_PLAYERS"), 1)
+	}
+
 	hub := &Hub{
-		ID:             id,
+		ID:             cfg.ID,
+		Config:         cfg,
 		ClientManager:  NewClientManager(),
 		HubManager:     hm,
 		OccupiedInMap:  sync.Map{},
@@ -34,15 +113,33 @@ func NewHub(hm *HubManager, id string) *Hub {
 		PositionChan:   make(chan *models.PlayerPosition),
 		Scores:         sync.Map{},
 		ActionChan:     make(chan *models.ItemAction),
+		AttackChan:     make(chan *models.Attack),
 		MsgChan:        make(chan *models.ChatMsg),
 		CurrentRound:   nil,
+		Schedule:       NewRoundScheduleFromConfig(clock.Now(), cfg.Lobby),
+		Clock:          clock,
+		Mode:           mode,
+		quit:           make(chan struct{}),
+		inputs:         make(map[string][]*playerInput),
+		stats:          make(map[string]*playerStats),
+		effects:        make(map[string]map[string]*models.Action),
+		entities:       make(map[string]*npc),
+		views:          make(map[string]map[string]*models.ViewChange),
+		teams:          make(map[string]string),
+		teamChoices:    make(map[string]string),
+		seeds:          newLockedRand(time.Now().UnixNano()),
 		mu:             sync.RWMutex{},
 		obstaclesMu:    sync.RWMutex{},
 	}
 
 	hub.CurrentRound = hub.NewRound()
+	if hub.hasFog() {
+		hub.ClientManager.SetViewFilter(hub.canSee)
+	}
+	hub.rng = newLockedRand(hub.seeds.Int63())
+	hub.playRng = newLockedRand(hub.seeds.Int63())
 
-	return hub
+	return hub, nil
 }
 
 func (hm *HubManager) RegisterHub(h *Hub) {
@@ -56,6 +153,7 @@ type ClientManager struct {
 	clients          map[*Client]bool
 	clientsById      map[string]*Client
 	clientsConnState map[string]bool
+	viewFilter       func(client *Client, msg *models.GameMsg) bool // nil shows clients everything
 	mu               sync.RWMutex
 }
 
@@ -100,6 +198,37 @@ func (cm *ClientManager) GetClients() map[*Client]bool {
 	return cm.clients
 }
 
+// SortedClients returns the clients ordered by id, for a stable iteration order.
+func (cm *ClientManager) SortedClients() []*Client {
+	cm.mu.RLock()
+	clients := make([]*Client, 0, len(cm.clients))
+	for client := range cm.clients {
+		clients = append(clients, client)
+	}
+	cm.mu.RUnlock()
+
+	sort.Slice(clients, func(i, j int) bool { return clients[i].ID < clients[j].ID })
+	return clients
+}
+
+func (cm *ClientManager) IsConnected(userId string) bool {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	return cm.clientsConnState[userId]
+}
+
+func (cm *ClientManager) CountConnected() int {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	count := 0
+	for _, isOnline := range cm.clientsConnState {
+		if isOnline {
+			count++
+		}
+	}
+	return count
+}
+
 func (cm *ClientManager) GetDisconnectedClients() []*Client {
 	cm.mu.RLock()
 	defer cm.mu.RUnlock()
@@ -119,10 +248,26 @@ func (cm *ClientManager) GetClientByID(userId string) (*Client, bool) {
 	return client, exists
 }
 
+// SetViewFilter hides the messages a client must not see, e.g. the players
+// in the fog of war. Every send of the ClientManager goes through it.
+func (cm *ClientManager) SetViewFilter(filter func(client *Client, msg *models.GameMsg) bool) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.viewFilter = filter
+}
+
+// CanSee reports whether the view filter lets a client see a message.
+func (cm *ClientManager) CanSee(client *Client, msg *models.GameMsg) bool {
+	return cm.viewFilter == nil || cm.viewFilter(client, msg)
+}
+
 func (cm *ClientManager) BroadcastAll(msg *models.GameMsg) {
 	cm.mu.RLock()
 	defer cm.mu.RUnlock()
 	for client := range cm.clients {
+		if !cm.CanSee(client, msg) {
+			continue
+		}
 		select {
 		case client.Send <- msg:
 		default:
@@ -132,6 +277,25 @@ func (cm *ClientManager) BroadcastAll(msg *models.GameMsg) {
 	}
 }
 
+// SendEach sends every client the message built for it, a nil message skips
+// the client. Slow clients are dropped instead of blocking the others, a
+// missed update would leave their view out of sync.
+func (cm *ClientManager) SendEach(build func(client *Client) *models.GameMsg) {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	for client := range cm.clients {
+		msg := build(client)
+		if msg == nil {
+			continue
+		}
+		select {
+		case client.Send <- msg:
+		default:
+			client.drop()
+		}
+	}
+}
+
 func (cm *ClientManager) SendToClient(userId string, msg *models.GameMsg) {
 	cm.mu.RLock()
 	defer cm.mu.RUnlock()
@@ -140,6 +304,9 @@ func (cm *ClientManager) SendToClient(userId string, msg *models.GameMsg) {
 		zap.S().Errorf("client %s not found", userId)
 		return
 	}
+	if !cm.CanSee(client, msg) {
+		return
+	}
 
 	select {
 	case client.Send <- msg:
@@ -0,0 +1,215 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"pickup/internal/global"
+	"pickup/pkg/models"
+	"strings"
+)
+
+// RandomMap is the map name that keeps the generated layout in a rotation.
+const RandomMap = "random"
+
+// cells of the ASCII grid of a map file
+const (
+	mapCellObstacle = '#'
+	mapCellSpawn    = 'S'
+	mapCellItemZone = '*'
+	mapCellFree     = '.'
+)
+
+// GameMap is a hand-authored layout read from a JSON file in MAP_DIR. The
+// cells can be listed or drawn in Grid, one string per row.
+type GameMap struct {
+	Name      string             `json:"name"`
+	Width     int                `json:"width"`
+	Height    int                `json:"height"`
+	Grid      []string           `json:"grid,omitempty"`
+	Obstacles []*models.Position `json:"obstacles,omitempty"`
+	Spawns    []*models.Position `json:"spawns,omitempty"`
+	ItemZones []*models.Position `json:"itemZones,omitempty"` // random items only go here, anywhere if empty
+	Items     []*MapItem         `json:"items,omitempty"`     // placed at the start of every round
+}
+
+// MapItem is an item a map places at a fixed cell.
+type MapItem struct {
+	Type string `json:"type"`
+	X    int    `json:"x"`
+	Y    int    `json:"y"`
+}
+
+var Maps = map[string]*GameMap{}
+
+// LoadMaps reads and validates every map file of a directory, the maps are
+// named after their file unless they set a name.
+func LoadMaps(dir string) (map[string]*GameMap, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list maps: %w", err)
+	}
+
+	maps := make(map[string]*GameMap, len(files))
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read map %s: %w", file, err)
+		}
+		gameMap := &GameMap{}
+		if err := json.Unmarshal(data, gameMap); err != nil {
+			return nil, fmt.Errorf("failed to parse map %s: %w", file, err)
+		}
+		if gameMap.Name == "" {
+			gameMap.Name = strings.TrimSuffix(filepath.Base(file), ".json")
+		}
+		if err := gameMap.parseGrid(); err != nil {
+			return nil, fmt.Errorf("map %s: %w", gameMap.Name, err)
+		}
+		if err := gameMap.validate(); err != nil {
+			return nil, fmt.Errorf("map %s: %w", gameMap.Name, err)
+		}
+		if _, exists := maps[gameMap.Name]; exists || gameMap.Name == RandomMap {
+			return nil, fmt.Errorf("map name %s is already taken", gameMap.Name)
+		}
+		maps[gameMap.Name] = gameMap
+	}
+	return maps, nil
+}
+
+// parseGrid adds the cells drawn in the ASCII grid to the cell lists.
+func (m *GameMap) parseGrid() error {
+	if len(m.Grid) == 0 {
+		return nil
+	}
+	if m.Height == 0 {
+		m.Height = len(m.Grid)
+	}
+	if len(m.Grid) != m.Height {
+		return fmt.Errorf("grid has %d rows, expected %d", len(m.Grid), m.Height)
+	}
+	for y, row := range m.Grid {
+		if m.Width == 0 {
+			m.Width = len(row)
+		}
+		if len(row) != m.Width {
+			return fmt.Errorf("grid row %d has %d cells, expected %d", y, len(row), m.Width)
+		}
+		for x, cell := range row {
+			switch cell {
+			case mapCellObstacle:
+				m.Obstacles = append(m.Obstacles, &models.Position{X: x, Y: y})
+			case mapCellSpawn:
+				m.Spawns = append(m.Spawns, &models.Position{X: x, Y: y})
+			case mapCellItemZone:
+				m.ItemZones = append(m.ItemZones, &models.Position{X: x, Y: y})
+			case mapCellFree:
+			default:
+				return fmt.Errorf("unknown cell %q at (%d, %d)", cell, x, y)
+			}
+		}
+	}
+	return nil
+}
+
+// validate checks the cells are on the grid and reachable, and that the
+// map has enough spawn points.
+func (m *GameMap) validate() error {
+	if m.Width <= 0 || m.Height <= 0 {
+		return fmt.Errorf("size %dx%d is not a grid", m.Width, m.Height)
+	}
+
+	blocked := make(map[models.Position]bool, len(m.Obstacles))
+	for _, obstacle := range m.Obstacles {
+		if !m.inBounds(obstacle) {
+			return fmt.Errorf("obstacle (%d, %d) is out of the grid", obstacle.X, obstacle.Y)
+		}
+		blocked[*obstacle] = true
+	}
+	if !isConnected(m.Width, m.Height, blocked) {
+		return fmt.Errorf("obstacles cut off part of the grid")
+	}
+
+	check := func(what string, cells []*models.Position) error {
+		for _, cell := range cells {
+			if !m.inBounds(cell) {
+				return fmt.Errorf("%s (%d, %d) is out of the grid", what, cell.X, cell.Y)
+			}
+			if blocked[*cell] {
+				return fmt.Errorf("%s (%d, %d) is on an obstacle", what, cell.X, cell.Y)
+			}
+		}
+		return nil
+	}
+	if err := check("spawn", m.Spawns); err != nil {
+		return err
+	}
+	if err := check("item zone", m.ItemZones); err != nil {
+		return err
+	}
+
+	placed := make(map[models.Position]bool, len(m.Items))
+	for _, item := range m.Items {
+		cell := &models.Position{X: item.X, Y: item.Y}
+		if err := check("item", []*models.Position{cell}); err != nil {
+			return err
+		}
+		if placed[*cell] {
+			return fmt.Errorf("two items at (%d, %d)", item.X, item.Y)
+		}
+		placed[*cell] = true
+		if _, ok := Catalogue.Get(item.Type); !ok {
+			return fmt.Errorf("item (%d, %d) has unknown type %s", item.X, item.Y, item.Type)
+		}
+	}
+
+	if minSpawns := global.Dv.GetInt("MAP_MIN_SPAWNS"); len(m.Spawns) < minSpawns {
+		return fmt.Errorf("has %d spawn points, at least %d are needed", len(m.Spawns), minSpawns)
+	}
+	return nil
+}
+
+func (m *GameMap) inBounds(position *models.Position) bool {
+	return position.X >= 0 && position.X < m.Width && position.Y >= 0 && position.Y < m.Height
+}
+
+// GridSize returns the width and height of the board of the current round,
+// a map sets its own size and a scaled arena the one picked for the players.
+func (h *Hub) GridSize() (int, int) {
+	if gameMap := h.gameMap.Load(); gameMap != nil {
+		return gameMap.Width, gameMap.Height
+	}
+	if size := h.arena.Load(); size != nil {
+		return size.Width, size.Height
+	}
+	return h.Config.Width, h.Config.Height
+}
+
+// inGrid reports whether a cell is on the board of the current round.
+func (h *Hub) inGrid(x, y int) bool {
+	width, height := h.GridSize()
+	return x >= 0 && x < width && y >= 0 && y < height
+}
+
+// mapName returns the name of the current map, empty when generated.
+func (h *Hub) mapName() string {
+	if gameMap := h.gameMap.Load(); gameMap != nil {
+		return gameMap.Name
+	}
+	return ""
+}
+
+// nextMap moves the hub to the next map of its rotation, nil is the
+// generated layout.
+func (h *Hub) nextMap() *GameMap {
+	if len(h.Config.Maps) == 0 {
+		return nil
+	}
+	name := h.Config.Maps[h.mapIndex%len(h.Config.Maps)]
+	h.mapIndex++
+
+	gameMap := Maps[name]
+	h.gameMap.Store(gameMap)
+	return gameMap
+}
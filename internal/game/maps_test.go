@@ -0,0 +1,62 @@
+package game
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadMapsReadsTheShippedMaps(t *testing.T) {
+	for _, name := range []string{"cross", "pillars"} {
+		gameMap, ok := Maps[name]
+		if !ok {
+			t.Fatalf("map %s was not loaded", name)
+		}
+		if gameMap.Width == 0 || gameMap.Height == 0 || len(gameMap.Spawns) == 0 {
+			t.Fatalf("map %s is %dx%d with %d spawns", name, gameMap.Width, gameMap.Height, len(gameMap.Spawns))
+		}
+	}
+}
+
+func TestLoadMapsRejectsInvalidMaps(t *testing.T) {
+	setConfig(t, "MAP_MIN_SPAWNS", 2)
+	for _, test := range []struct {
+		name string
+		file string
+		err  string
+	}{
+		{"cut off", `{"grid": ["S.#..", "..#.S", "..#.."]}`, "cut off part of the grid"},
+		{"ragged rows", `{"grid": ["S...S", "..."]}`, "grid row 1 has 3 cells"},
+		{"unknown cell", `{"grid": ["S..?S"]}`, "unknown cell"},
+		{"spawn on an obstacle", `{"width": 3, "height": 2, "obstacles": [{"x": 1, "y": 0}], "spawns": [{"x": 0, "y": 0}, {"x": 1, "y": 0}]}`, "spawn (1, 0) is on an obstacle"},
+		{"out of the grid", `{"width": 3, "height": 1, "spawns": [{"x": 0, "y": 0}, {"x": 3, "y": 0}]}`, "spawn (3, 0) is out of the grid"},
+		{"too few spawns", `{"grid": ["S...."]}`, "has 1 spawn points"},
+		{"unknown item", `{"grid": ["S...S"], "items": [{"type": "ruby", "x": 2, "y": 0}]}`, "unknown type ruby"},
+		{"two items on a cell", `{"grid": ["S...S"], "items": [{"type": "coin", "x": 2, "y": 0}, {"type": "diamond", "x": 2, "y": 0}]}`, "two items at (2, 0)"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "broken.json"), []byte(test.file), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := LoadMaps(dir)
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("got error %v, want one about %q", err, test.err)
+			}
+		})
+	}
+}
+
+func TestLoadMapsRejectsTakenNames(t *testing.T) {
+	setConfig(t, "MAP_MIN_SPAWNS", 2)
+	dir := t.TempDir()
+	for _, file := range []string{"one.json", "two.json"} {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(`{"name": "same", "grid": ["S...S"]}`), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := LoadMaps(dir); err == nil || !strings.Contains(err.Error(), "already taken") {
+		t.Fatalf("got error %v, want the name to be taken", err)
+	}
+}
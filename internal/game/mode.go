@@ -0,0 +1,91 @@
+package game
+
+import (
+	"fmt"
+	"pickup/pkg/models"
+	"time"
+)
+
+// GameMode holds the rules of a room. The hub owns the board and the round
+// cycle, and delegates map setup, player input, scoring and win conditions
+// to its mode. A mode instance belongs to a single hub.
+type GameMode interface {
+	Name() string
+	// SetupMap places obstacles and items for a new round.
+	SetupMap(h *Hub)
+	// HandleAction resolves an action key press and awards any score.
+	HandleAction(h *Hub, action *models.ItemAction) error
+	// OnPlayerMoved is called after a position update has been accepted.
+	OnPlayerMoved(h *Hub, userId string, position *models.Position)
+	// OnTick advances the timers owned by the mode while the round is playing.
+	OnTick(h *Hub, now time.Time)
+	// Winners returns the ids of the players winning the round so far.
+	Winners(h *Hub) []string
+}
+
+// teamMode is implemented by modes that are only played in teams, their
+// rooms get two teams unless they set TEAMS.
+type teamMode interface {
+	teamMode()
+}
+
+// stateSyncer is implemented by modes with state of their own to send with
+// the state sync of a client.
+type stateSyncer interface {
+	SyncState(h *Hub, client *Client)
+}
+
+// playerTagger is implemented by modes where players tag each other by
+// bumping into or attacking them.
+type playerTagger interface {
+	OnPlayerTagged(h *Hub, userId, targetId string)
+}
+
+// startPicker is implemented by modes that start players on cells of their
+// own, players go anywhere when none of them is free.
+type startPicker interface {
+	StartCells(h *Hub, userId string) []*models.Position
+}
+
+const DefaultGameMode = "classic"
+
+var gameModes = map[string]func() GameMode{
+	"classic":   func() GameMode { return NewClassicMode() },
+	"bomberman": func() GameMode { return NewBombermanMode() },
+	"ctf":       func() GameMode { return NewCaptureTheFlagMode() },
+	"koth":      func() GameMode { return NewKingOfTheHillMode() },
+}
+
+// NewGameMode returns a fresh instance of the named mode.
+func NewGameMode(name string) (GameMode, error) {
+	if name == "" {
+		name = DefaultGameMode
+	}
+	newMode, ok := gameModes[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown game mode: %s", name)
+	}
+	return newMode(), nil
+}
+
+// topScorers returns the players sharing the highest score, ignoring rounds
+// where nobody scored. In team rooms the best team wins together.
+func (h *Hub) topScorers() []string {
+	if h.hasTeams() {
+		return h.topTeams()
+	}
+	best := 0
+	winners := make([]string, 0)
+	h.Scores.Range(func(key, value interface{}) bool {
+		userId, score := key.(string), value.(int)
+		switch {
+		case score > best:
+			best = score
+			winners = []string{userId}
+		case score == best && score > 0:
+			winners = append(winners, userId)
+		}
+		return true
+	})
+	return winners
+}
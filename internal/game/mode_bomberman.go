@@ -0,0 +1,233 @@
+package game
+
+import (
+	"fmt"
+	"go.uber.org/zap"
+	"pickup/internal/global"
+	"pickup/pkg/models"
+	"sort"
+	"sync"
+	"time"
+)
+
+// BombermanMode turns the action key into dropping a bomb on the player's
+// cell. Bombs explode after a fuse timed by the server, the blast travels in
+// straight lines, destroys obstacles, chains into other bombs and eliminates
+// (or penalizes) the players it hits.
+type BombermanMode struct {
+	bombs      map[string]*models.Bomb // map[positionString]*models.Bomb
+	eliminated map[string]bool
+	mu         sync.Mutex
+}
+
+var bombDirections = []models.Position{{X: 0, Y: -1}, {X: 0, Y: 1}, {X: -1, Y: 0}, {X: 1, Y: 0}}
+
+func NewBombermanMode() *BombermanMode {
+	return &BombermanMode{
+		bombs:      make(map[string]*models.Bomb),
+		eliminated: make(map[string]bool),
+	}
+}
+
+func (m *BombermanMode) Name() string {
+	return "bomberman"
+}
+
+func (m *BombermanMode) SetupMap(h *Hub) {
+	m.mu.Lock()
+	m.bombs = make(map[string]*models.Bomb)
+	m.eliminated = make(map[string]bool)
+	m.mu.Unlock()
+
+	h.InitObstacles()
+}
+
+func (m *BombermanMode) HandleAction(h *Hub, action *models.ItemAction) error {
+	// bombs are dropped where the server has the player, not where the client says
+	position, err := h.GetPlayerPositionByUserId(action.ID)
+	if err != nil {
+		return fmt.Errorf("failed to place bomb: %w", err)
+	}
+	positionString := fmt.Sprintf("%d-%d", position.X, position.Y)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.eliminated[action.ID] {
+		return fmt.Errorf("user %s is eliminated and cannot place bombs", action.ID)
+	}
+	if _, exists := m.bombs[positionString]; exists {
+		h.sendAlertToUser(action.ID, "There is already a bomb here")
+		return nil
+	}
+	if m.countBombsOf(action.ID) >= global.Dv.GetInt("BOMB_MAX_PER_PLAYER") {
+		h.sendAlertToUser(action.ID, "You have no bombs left, wait for one to explode")
+		return nil
+	}
+
+	bomb := &models.Bomb{
+		ID:        action.ID,
+		Radius:    global.Dv.GetInt("BOMB_RADIUS"),
+		ExplodeAt: h.Clock.Now().Add(time.Duration(global.Dv.GetInt("BOMB_FUSE_MS")) * time.Millisecond).UnixMilli(),
+		Position:  position.Position,
+	}
+	m.bombs[positionString] = bomb
+
+	h.broadcast(&models.GameMsg{
+		Type:    models.BombPlacedType,
+		Content: bomb,
+	})
+	return nil
+}
+
+func (m *BombermanMode) countBombsOf(userId string) int {
+	count := 0
+	for _, bomb := range m.bombs {
+		if bomb.ID == userId {
+			count++
+		}
+	}
+	return count
+}
+
+func (m *BombermanMode) OnPlayerMoved(h *Hub, userId string, position *models.Position) {}
+
+func (m *BombermanMode) OnTick(h *Hub, now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	queue := make([]*models.Bomb, 0)
+	for _, bomb := range m.bombs {
+		if bomb.ExplodeAt <= now.UnixMilli() {
+			queue = append(queue, bomb)
+		}
+	}
+	// keep explosions of the same tick in fuse order
+	sort.Slice(queue, func(i, j int) bool { return queue[i].ExplodeAt < queue[j].ExplodeAt })
+
+	for len(queue) > 0 {
+		bomb := queue[0]
+		queue = queue[1:]
+
+		positionString := fmt.Sprintf("%d-%d", bomb.X, bomb.Y)
+		if _, pending := m.bombs[positionString]; !pending {
+			// already set off by an earlier blast
+			continue
+		}
+		delete(m.bombs, positionString)
+
+		queue = append(queue, m.explode(h, bomb)...)
+	}
+}
+
+// explode resolves the blast of a single bomb and returns the bombs it sets off.
+func (m *BombermanMode) explode(h *Hub, bomb *models.Bomb) []*models.Bomb {
+	explosion := &models.Explosion{
+		ID:        bomb.ID,
+		Cells:     []*models.Position{{X: bomb.X, Y: bomb.Y}},
+		Destroyed: make([]*models.Position, 0),
+		Hit:       make([]string, 0),
+		Position:  bomb.Position,
+	}
+	chained := make([]*models.Bomb, 0)
+
+	for _, direction := range bombDirections {
+		for step := 1; step <= bomb.Radius; step++ {
+			x, y := bomb.X+direction.X*step, bomb.Y+direction.Y*step
+			if !h.inGrid(x, y) {
+				break
+			}
+			cell := &models.Position{X: x, Y: y}
+			explosion.Cells = append(explosion.Cells, cell)
+
+			// obstacles absorb the blast and are destroyed, even the ones
+			// attacks can't break
+			if h.removeObstacle(x, y) {
+				explosion.Destroyed = append(explosion.Destroyed, cell)
+				break
+			}
+			if next, ok := m.bombs[fmt.Sprintf("%d-%d", x, y)]; ok {
+				chained = append(chained, next)
+			}
+		}
+	}
+
+	hitCells := make(map[string]bool, len(explosion.Cells))
+	for _, cell := range explosion.Cells {
+		hitCells[fmt.Sprintf("%d-%d", cell.X, cell.Y)] = true
+	}
+	h.UsersInMap.Range(func(key, value interface{}) bool {
+		position := value.(*models.Position)
+		if hitCells[fmt.Sprintf("%d-%d", position.X, position.Y)] {
+			explosion.Hit = append(explosion.Hit, key.(string))
+		}
+		return true
+	})
+
+	h.broadcast(&models.GameMsg{
+		Type:    models.BombExplodedType,
+		Content: explosion,
+	})
+
+	for _, userId := range explosion.Hit {
+		m.hitPlayer(h, userId, bomb.ID)
+	}
+
+	zap.S().Debugf("hub: %v bomb of %v exploded at (%d, %d), destroyed %d obstacles, hit %v",
+		h.ID, bomb.ID, bomb.X, bomb.Y, len(explosion.Destroyed), explosion.Hit)
+	return chained
+}
+
+func (m *BombermanMode) hitPlayer(h *Hub, userId string, bomberId string) {
+	if bomberId != userId && h.hasEffect(userId, EffectShield) {
+		// the shield takes the blast
+		return
+	}
+	if !global.Dv.GetBool("BOMB_ELIMINATE") {
+		penalty := global.Dv.GetInt("BOMB_HIT_PENALTY")
+		h.broadcastSingleScore(userId, h.updateScore(userId, -penalty))
+		return
+	}
+
+	m.eliminated[userId] = true
+	h.removePlayerFromMap(userId)
+	h.broadcast(&models.GameMsg{
+		Type:    models.PlayerEliminatedType,
+		Content: &models.Elimination{ID: userId, By: bomberId},
+	})
+
+	// no reward for blowing yourself up
+	if bomberId != userId {
+		score := global.Dv.GetInt("BOMB_ELIMINATION_SCORE")
+		h.broadcastSingleScore(bomberId, h.updateScore(bomberId, score))
+	}
+	zap.S().Infof("hub: %v user %v eliminated by %v", h.ID, userId, bomberId)
+}
+
+// Winners returns the best scoring players among the ones still standing.
+func (m *BombermanMode) Winners(h *Hub) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	best := -1
+	winners := make([]string, 0)
+	h.UsersInMap.Range(func(key, value interface{}) bool {
+		userId := key.(string)
+		if m.eliminated[userId] {
+			return true
+		}
+		score := 0
+		if value, ok := h.Scores.Load(userId); ok {
+			score = value.(int)
+		}
+		switch {
+		case score > best:
+			best = score
+			winners = []string{userId}
+		case score == best:
+			winners = append(winners, userId)
+		}
+		return true
+	})
+	return winners
+}
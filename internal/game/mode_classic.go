@@ -0,0 +1,82 @@
+package game
+
+import (
+	"fmt"
+	"pickup/pkg/models"
+	"time"
+)
+
+// ClassicMode is the original pickup game: collect the catalogue items from a
+// board with random obstacles, the highest score wins. Items keep spawning
+// while the round is playing.
+type ClassicMode struct{}
+
+func NewClassicMode() *ClassicMode {
+	return &ClassicMode{}
+}
+
+func (m *ClassicMode) Name() string {
+	return "classic"
+}
+
+func (m *ClassicMode) SetupMap(h *Hub) {
+	h.InitObstacles()
+	h.InitCatalogueItems(h.rng)
+}
+
+func (m *ClassicMode) HandleAction(h *Hub, itemAction *models.ItemAction) error {
+	positionString := fmt.Sprintf("%d-%d", itemAction.Position.X, itemAction.Position.Y)
+	itemInMap, err := h.getItemInMap(positionString)
+	if err != nil {
+		return fmt.Errorf("failed to get item in map: %w", err)
+	}
+
+	// keep the item on the board for the players who can still score
+	if !h.canScore(itemAction.ID) {
+		h.sendAlertToUser(itemAction.ID, "Only the tied players can score in overtime")
+		return nil
+	}
+
+	def, ok := Catalogue.Get(itemInMap.Item.Type)
+	if !ok {
+		return fmt.Errorf("unknown item type: %s", itemInMap.Item.Type)
+	}
+	if def.Kind == ItemKindHazard {
+		// hazards can't be picked up, only stepped on
+		h.triggerHazard(itemAction.ID, itemInMap.Position)
+		return nil
+	}
+
+	h.ItemsInMap.Delete(positionString)
+	h.recordItemCollected(itemAction.ID, def.Type)
+	h.broadcastCollectedItem(itemInMap)
+
+	switch def.Kind {
+	case ItemKindCollect:
+		newScore := h.updateScore(itemAction.ID, itemInMap.Item.Value)
+		h.broadcastSingleScore(itemAction.ID, newScore)
+	case ItemKindEffect:
+		return h.applyItemEffect(itemAction.ID, def)
+	}
+	return nil
+}
+
+func (m *ClassicMode) SpawnOvertimeItems(h *Hub) []*models.ItemAction {
+	return h.InitCatalogueItems(h.playRng)
+}
+
+func (m *ClassicMode) DropItem(h *Hub, x, y int) {
+	h.dropItem(x, y)
+}
+
+func (m *ClassicMode) OnPlayerMoved(h *Hub, userId string, position *models.Position) {
+	h.triggerHazard(userId, position)
+}
+
+func (m *ClassicMode) OnTick(h *Hub, now time.Time) {
+	h.tickItemSpawner(now)
+}
+
+func (m *ClassicMode) Winners(h *Hub) []string {
+	return h.topScorers()
+}
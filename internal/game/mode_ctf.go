@@ -0,0 +1,274 @@
+package game
+
+import (
+	"fmt"
+	"go.uber.org/zap"
+	"pickup/internal/global"
+	"pickup/pkg/models"
+	"sort"
+	"sync"
+	"time"
+)
+
+// flag events sent with flagState
+const (
+	FlagPlaced   = "placed"
+	FlagTaken    = "taken"
+	FlagCarried  = "carried" // moved with its carrier, only sent to who sees the carrier
+	FlagDropped  = "dropped"
+	FlagReturned = "returned"
+	FlagCaptured = "captured"
+)
+
+// CaptureTheFlagMode is played in teams. Every team has a base on its side
+// of the board with its flag in the middle. The action key picks up an enemy
+// flag, carrying it into your own base captures it. A carrier tagged by an
+// opponent, by bumping into them or attacking them, drops the flag, which
+// goes back home after CTF_FLAG_RETURN_SEC or when a defender picks it up.
+type CaptureTheFlagMode struct {
+	bases map[string]map[models.Position]bool // map[teamString]cells
+	flags map[string]*ctfFlag                 // map[teamString]*ctfFlag
+	mu    sync.Mutex
+}
+
+// ctfFlag is the flag of a team, on the board or carried.
+type ctfFlag struct {
+	team     string
+	home     models.Position
+	position models.Position
+	carrier  string    // player holding the flag
+	returnAt time.Time // a dropped flag goes home then, zero when home or carried
+}
+
+func NewCaptureTheFlagMode() *CaptureTheFlagMode {
+	return &CaptureTheFlagMode{
+		bases: make(map[string]map[models.Position]bool),
+		flags: make(map[string]*ctfFlag),
+	}
+}
+
+func (m *CaptureTheFlagMode) Name() string {
+	return "ctf"
+}
+
+func (m *CaptureTheFlagMode) teamMode() {}
+
+// SetupMap lays out the bases after the obstacles, clearing the obstacles
+// that fell on them, and puts every flag home. There are no items to collect.
+func (m *CaptureTheFlagMode) SetupMap(h *Hub) {
+	h.InitObstacles()
+
+	width, height := h.GridSize()
+	size := min(global.Dv.GetInt("CTF_BASE_SIZE"), width, height)
+	size = max(size, 1)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.bases = make(map[string]map[models.Position]bool)
+	m.flags = make(map[string]*ctfFlag)
+	for i, team := range h.teamNames() {
+		// red left, blue right, green top, yellow bottom
+		corner := [][2]int{
+			{0, (height - size) / 2},
+			{width - size, (height - size) / 2},
+			{(width - size) / 2, 0},
+			{(width - size) / 2, height - size},
+		}[i]
+		cells := make(map[models.Position]bool, size*size)
+		for x := corner[0]; x < corner[0]+size; x++ {
+			for y := corner[1]; y < corner[1]+size; y++ {
+				cells[models.Position{X: x, Y: y}] = true
+				h.removeObstacle(x, y)
+			}
+		}
+		m.bases[team] = cells
+
+		home := models.Position{X: corner[0] + size/2, Y: corner[1] + size/2}
+		m.flags[team] = &ctfFlag{team: team, home: home, position: home}
+	}
+}
+
+func (m *CaptureTheFlagMode) HandleAction(h *Hub, action *models.ItemAction) error {
+	// flags are picked up where the server has the player
+	position, err := h.GetPlayerPositionByUserId(action.ID)
+	if err != nil {
+		return fmt.Errorf("failed to pick up a flag: %w", err)
+	}
+	team := h.teamOf(action.ID)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, flag := range m.sortedFlags() {
+		if flag.carrier != "" || flag.position != *position.Position {
+			continue
+		}
+		if flag.team == team {
+			if flag.position == flag.home {
+				return nil
+			}
+			m.returnFlag(h, flag, action.ID)
+			if points := global.Dv.GetInt("CTF_RETURN_SCORE"); points > 0 {
+				h.broadcastSingleScore(action.ID, h.updateScore(action.ID, points))
+			}
+			return nil
+		}
+		if m.carriedBy(action.ID) != nil {
+			h.sendAlertToUser(action.ID, "You can only carry one flag")
+			return nil
+		}
+		flag.carrier = action.ID
+		flag.returnAt = time.Time{}
+		m.broadcastFlag(h, flag, FlagTaken, action.ID)
+		zap.S().Debugf("hub: %v user %v took the %v flag", h.ID, action.ID, flag.team)
+		return nil
+	}
+	h.sendAlertToUser(action.ID, "There is no flag here")
+	return nil
+}
+
+// OnPlayerMoved moves a carried flag with its carrier, and captures it once
+// the carrier is in their own base.
+func (m *CaptureTheFlagMode) OnPlayerMoved(h *Hub, userId string, position *models.Position) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	flag := m.carriedBy(userId)
+	if flag == nil || flag.position == *position {
+		return
+	}
+	flag.position = *position
+
+	if !m.bases[h.teamOf(userId)][*position] {
+		m.broadcastFlag(h, flag, FlagCarried, userId)
+		return
+	}
+
+	flag.carrier = ""
+	flag.position = flag.home
+	m.broadcastFlag(h, flag, FlagCaptured, userId)
+	if points := global.Dv.GetInt("CTF_CAPTURE_SCORE"); points > 0 {
+		h.broadcastSingleScore(userId, h.updateScore(userId, points))
+	}
+	zap.S().Infof("hub: %v user %v captured the %v flag", h.ID, userId, flag.team)
+}
+
+// OnPlayerTagged drops the flag of a carrier tagged by an opponent.
+func (m *CaptureTheFlagMode) OnPlayerTagged(h *Hub, userId, targetId string) {
+	if h.sameTeam(userId, targetId) {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if flag := m.carriedBy(targetId); flag != nil {
+		m.dropFlag(h, flag, userId, h.Clock.Now())
+	}
+}
+
+// OnTick sends dropped flags home once their timer is up. A carrier that
+// left the board, or was put back on a start cell without moving there,
+// drops the flag where it was last carried.
+func (m *CaptureTheFlagMode) OnTick(h *Hub, now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, flag := range m.sortedFlags() {
+		if flag.carrier != "" {
+			position, ok := h.UsersInMap.Load(flag.carrier)
+			if !ok || *position.(*models.Position) != flag.position {
+				m.dropFlag(h, flag, "", now)
+			}
+			continue
+		}
+		if !flag.returnAt.IsZero() && !now.Before(flag.returnAt) {
+			m.returnFlag(h, flag, "")
+		}
+	}
+}
+
+func (m *CaptureTheFlagMode) Winners(h *Hub) []string {
+	return h.topScorers()
+}
+
+// StartCells starts players in their team's base.
+func (m *CaptureTheFlagMode) StartCells(h *Hub, userId string) []*models.Position {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cells := make([]*models.Position, 0)
+	for cell := range m.bases[h.teamOf(userId)] {
+		cells = append(cells, &models.Position{X: cell.X, Y: cell.Y})
+	}
+	// a stable order so a seed gives the same spawns
+	sort.Slice(cells, func(i, j int) bool {
+		return cells[i].X < cells[j].X || cells[i].X == cells[j].X && cells[i].Y < cells[j].Y
+	})
+	return cells
+}
+
+// SyncState sends the bases and where the flags are.
+func (m *CaptureTheFlagMode) SyncState(h *Hub, client *Client) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, team := range h.teamNames() {
+		base := &models.Base{Team: team, Cells: make([]*models.Position, 0)}
+		for cell := range m.bases[team] {
+			base.Cells = append(base.Cells, &models.Position{X: cell.X, Y: cell.Y})
+		}
+		client.Send <- &models.GameMsg{Type: models.BaseType, Content: base}
+	}
+	for _, flag := range m.sortedFlags() {
+		client.Send <- &models.GameMsg{Type: models.FlagStateType, Content: m.flagState(flag, FlagPlaced, "")}
+	}
+}
+
+// the helpers below expect m.mu to be held
+
+func (m *CaptureTheFlagMode) carriedBy(userId string) *ctfFlag {
+	for _, flag := range m.flags {
+		if flag.carrier == userId {
+			return flag
+		}
+	}
+	return nil
+}
+
+func (m *CaptureTheFlagMode) sortedFlags() []*ctfFlag {
+	flags := make([]*ctfFlag, 0, len(m.flags))
+	for _, flag := range m.flags {
+		flags = append(flags, flag)
+	}
+	sort.Slice(flags, func(i, j int) bool { return flags[i].team < flags[j].team })
+	return flags
+}
+
+func (m *CaptureTheFlagMode) dropFlag(h *Hub, flag *ctfFlag, by string, now time.Time) {
+	carrier := flag.carrier
+	flag.carrier = ""
+	flag.returnAt = now.Add(time.Duration(global.Dv.GetInt("CTF_FLAG_RETURN_SEC")) * time.Second)
+	m.broadcastFlag(h, flag, FlagDropped, by)
+	zap.S().Debugf("hub: %v user %v dropped the %v flag", h.ID, carrier, flag.team)
+}
+
+func (m *CaptureTheFlagMode) returnFlag(h *Hub, flag *ctfFlag, by string) {
+	flag.position = flag.home
+	flag.returnAt = time.Time{}
+	m.broadcastFlag(h, flag, FlagReturned, by)
+}
+
+func (m *CaptureTheFlagMode) broadcastFlag(h *Hub, flag *ctfFlag, event, by string) {
+	h.broadcast(&models.GameMsg{Type: models.FlagStateType, Content: m.flagState(flag, event, by)})
+}
+
+func (m *CaptureTheFlagMode) flagState(flag *ctfFlag, event, by string) *models.Flag {
+	state := &models.Flag{
+		Team:     flag.team,
+		Event:    event,
+		Carrier:  flag.carrier,
+		By:       by,
+		Position: &models.Position{X: flag.position.X, Y: flag.position.Y},
+	}
+	if !flag.returnAt.IsZero() {
+		state.ReturnAt = flag.returnAt.UnixMilli()
+	}
+	return state
+}
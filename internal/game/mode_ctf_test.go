@@ -0,0 +1,32 @@
+package game
+
+import (
+	"testing"
+
+	"pickup/pkg/models"
+)
+
+func TestCarriedFlagIsOnlySeenWithItsCarrier(t *testing.T) {
+	h, _ := newTestHub(t, RoomConfig{ID: "ctf", Mode: "ctf", Teams: 2, VisibilityRadius: 3})
+	carrier, teammate, opponent := addClient(h, "carrier"), addClient(h, "teammate"), addClient(h, "opponent")
+	h.teams = map[string]string{"carrier": "blue", "teammate": "blue", "opponent": "red"}
+	h.Mode.SetupMap(h)
+
+	ctf := h.Mode.(*CaptureTheFlagMode)
+	flag := ctf.flags["red"]
+	flag.carrier = "carrier"
+	flag.position = models.Position{X: 7, Y: 7}
+	placePlayer(h, "carrier", 7, 7)
+	placePlayer(h, "teammate", 14, 0)
+	placePlayer(h, "opponent", 0, 0)
+
+	moved := &models.Position{X: 7, Y: 8}
+	placePlayer(h, "carrier", moved.X, moved.Y)
+	ctf.OnPlayerMoved(h, "carrier", moved)
+
+	for client, want := range map[*Client]int{carrier: 1, teammate: 1, opponent: 0} {
+		if got := flushedTypes(h, client); len(got) != want {
+			t.Fatalf("%s got %v for a step of the carrier, want %d flag update", client.ID, got, want)
+		}
+	}
+}
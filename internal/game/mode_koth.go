@@ -0,0 +1,219 @@
+package game
+
+import (
+	"fmt"
+	"go.uber.org/zap"
+	"pickup/internal/global"
+	"pickup/pkg/models"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// KingOfTheHillMode scores the time spent in a zone of cells that moves to
+// a new spot every KOTH_ZONE_MOVE_SEC. The players standing in the zone gain
+// KOTH_SCORE_PER_SEC for every full second they hold it, unless it is
+// contested by another player, or by another team in team rooms, when
+// nobody scores.
+type KingOfTheHillMode struct {
+	corner    models.Position // top left cell of the zone
+	size      int
+	moveAt    time.Time // zero until the round starts playing
+	control   *models.ZoneControl
+	heldSince time.Time // start of the second the holders are scoring
+	mu        sync.Mutex
+}
+
+func NewKingOfTheHillMode() *KingOfTheHillMode {
+	return &KingOfTheHillMode{control: &models.ZoneControl{Holders: make([]string, 0)}}
+}
+
+func (m *KingOfTheHillMode) Name() string {
+	return "koth"
+}
+
+// SetupMap places the obstacles and the first zone, there are no items.
+func (m *KingOfTheHillMode) SetupMap(h *Hub) {
+	h.InitObstacles()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	width, height := h.GridSize()
+	m.size = max(min(global.Dv.GetInt("KOTH_ZONE_SIZE"), width, height), 1)
+	m.moveAt = time.Time{}
+	m.control = &models.ZoneControl{Holders: make([]string, 0)}
+	m.heldSince = time.Time{}
+	m.corner = m.nextCorner(h, h.rng, models.Position{X: -1, Y: -1})
+}
+
+func (m *KingOfTheHillMode) HandleAction(h *Hub, action *models.ItemAction) error {
+	return fmt.Errorf("user %s pressed the action key, there is nothing to pick up in %s", action.ID, m.Name())
+}
+
+func (m *KingOfTheHillMode) OnPlayerMoved(h *Hub, userId string, position *models.Position) {}
+
+// OnTick moves the zone when it is due, then scores the players holding it.
+func (m *KingOfTheHillMode) OnTick(h *Hub, now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	interval := time.Duration(global.Dv.GetInt("KOTH_ZONE_MOVE_SEC")) * time.Second
+	switch {
+	case m.moveAt.IsZero():
+		m.moveAt = now.Add(interval)
+		h.broadcast(&models.GameMsg{Type: models.ZonePositionType, Content: m.zone()})
+	case interval > 0 && !now.Before(m.moveAt):
+		m.corner = m.nextCorner(h, h.playRng, m.corner)
+		m.moveAt = now.Add(interval)
+		h.broadcast(&models.GameMsg{Type: models.ZonePositionType, Content: m.zone()})
+		zap.S().Debugf("hub: %v zone moved to %v", h.ID, m.corner)
+	}
+
+	control := m.zoneControl(h)
+	if control.Contested != m.control.Contested || !slices.Equal(control.Holders, m.control.Holders) {
+		m.control = control
+		m.heldSince = now
+		m.broadcastControl(h)
+	}
+	if control.Contested || len(control.Holders) == 0 {
+		return
+	}
+	// scores once a second, the tick rate only decides how late
+	for !now.Before(m.heldSince.Add(time.Second)) {
+		m.heldSince = m.heldSince.Add(time.Second)
+		h.awardScore(control.Holders, global.Dv.GetInt("KOTH_SCORE_PER_SEC"))
+	}
+}
+
+func (m *KingOfTheHillMode) Winners(h *Hub) []string {
+	return h.topScorers()
+}
+
+// SyncState sends where the zone is and who holds it.
+func (m *KingOfTheHillMode) SyncState(h *Hub, client *Client) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	client.Send <- &models.GameMsg{Type: models.ZonePositionType, Content: m.zone()}
+	client.Send <- &models.GameMsg{Type: models.ZoneControlType, Content: m.controlSeenBy(h, client.ID, h.sightOf(client.ID))}
+}
+
+// the helpers below expect m.mu to be held
+
+// broadcastControl tells every player who holds the zone, under fog of war
+// players only learn about the holders they see.
+func (m *KingOfTheHillMode) broadcastControl(h *Hub) {
+	if !h.hasFog() {
+		h.broadcast(&models.GameMsg{Type: models.ZoneControlType, Content: m.control})
+		return
+	}
+	sights := h.sights()
+	for _, client := range h.ClientManager.SortedClients() {
+		h.sendToClient(client.ID, &models.GameMsg{
+			Type:    models.ZoneControlType,
+			Content: m.controlSeenBy(h, client.ID, sights[client.ID]),
+		})
+	}
+}
+
+// controlSeenBy is the control of the zone as far as a player sees it, the
+// team and the contest only count the holders in sight.
+func (m *KingOfTheHillMode) controlSeenBy(h *Hub, userId string, s *sight) *models.ZoneControl {
+	seen := make([]string, 0)
+	for _, holderId := range m.control.Holders {
+		if h.playerVisibleTo(userId, s, holderId) {
+			seen = append(seen, holderId)
+		}
+	}
+	return controlOf(h, seen)
+}
+
+func (m *KingOfTheHillMode) inZone(position *models.Position) bool {
+	return position.X >= m.corner.X && position.X < m.corner.X+m.size &&
+		position.Y >= m.corner.Y && position.Y < m.corner.Y+m.size
+}
+
+// zoneControl lists the players in the zone, which is contested once they
+// aren't a single player or a single team.
+func (m *KingOfTheHillMode) zoneControl(h *Hub) *models.ZoneControl {
+	holders := make([]string, 0)
+	h.UsersInMap.Range(func(key, value interface{}) bool {
+		if m.inZone(value.(*models.Position)) {
+			holders = append(holders, key.(string))
+		}
+		return true
+	})
+	sort.Strings(holders)
+	return controlOf(h, holders)
+}
+
+// controlOf builds the control of the zone held by the given players.
+func controlOf(h *Hub, holders []string) *models.ZoneControl {
+	control := &models.ZoneControl{Holders: holders}
+	if !h.hasTeams() {
+		control.Contested = len(control.Holders) > 1
+		return control
+	}
+	for _, userId := range control.Holders {
+		team := h.teamOf(userId)
+		if control.Team != "" && team != control.Team {
+			control.Contested = true
+			control.Team = ""
+			break
+		}
+		control.Team = team
+	}
+	return control
+}
+
+// nextCorner picks a new spot for the zone, away from the current one and
+// clear of obstacles where the board allows it.
+func (m *KingOfTheHillMode) nextCorner(h *Hub, rng *lockedRand, current models.Position) models.Position {
+	width, height := h.GridSize()
+	free := make([]models.Position, 0)
+	others := make([]models.Position, 0)
+	for x := 0; x+m.size <= width; x++ {
+		for y := 0; y+m.size <= height; y++ {
+			corner := models.Position{X: x, Y: y}
+			if corner == current {
+				continue
+			}
+			if m.hasObstacle(h, corner) {
+				others = append(others, corner)
+			} else {
+				free = append(free, corner)
+			}
+		}
+	}
+	if len(free) == 0 {
+		free = others
+	}
+	if len(free) == 0 {
+		return current
+	}
+	return free[rng.Intn(len(free))]
+}
+
+func (m *KingOfTheHillMode) hasObstacle(h *Hub, corner models.Position) bool {
+	for x := corner.X; x < corner.X+m.size; x++ {
+		for y := corner.Y; y < corner.Y+m.size; y++ {
+			if h.isObstacleAt(x, y) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (m *KingOfTheHillMode) zone() *models.Zone {
+	zone := &models.Zone{Cells: make([]*models.Position, 0, m.size*m.size)}
+	for x := m.corner.X; x < m.corner.X+m.size; x++ {
+		for y := m.corner.Y; y < m.corner.Y+m.size; y++ {
+			zone.Cells = append(zone.Cells, &models.Position{X: x, Y: y})
+		}
+	}
+	if !m.moveAt.IsZero() {
+		zone.MoveAt = m.moveAt.UnixMilli()
+	}
+	return zone
+}
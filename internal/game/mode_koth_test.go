@@ -0,0 +1,84 @@
+package game
+
+import (
+	"testing"
+	"time"
+
+	"pickup/pkg/models"
+)
+
+// newKothHub sets up a king of the hill room with the zone in the top left
+// corner.
+func newKothHub(t *testing.T, cfg RoomConfig) (*Hub, *fakeClock, *KingOfTheHillMode) {
+	t.Helper()
+	cfg.Mode = "koth"
+	h, clock := newTestHub(t, cfg)
+	koth := h.Mode.(*KingOfTheHillMode)
+	koth.SetupMap(h)
+	koth.corner = models.Position{X: 0, Y: 0}
+	return h, clock, koth
+}
+
+func TestZoneScoresPerSecondWhateverTheTickRate(t *testing.T) {
+	setConfig(t, "KOTH_SCORE_PER_SEC", 5)
+	for _, tick := range []time.Duration{50 * time.Millisecond, 100 * time.Millisecond, 300 * time.Millisecond} {
+		h, clock, koth := newKothHub(t, RoomConfig{ID: "koth"})
+		placePlayer(h, "a", 1, 1)
+
+		scores := 0
+		for end := clock.Now().Add(3 * time.Second); clock.Now().Before(end); {
+			koth.OnTick(h, clock.Now())
+			scores += scoreMessages(h)
+			h.outbox = nil
+			clock.Advance(tick)
+		}
+		koth.OnTick(h, clock.Now())
+		scores += scoreMessages(h)
+
+		if score, _ := h.Scores.Load("a"); score != 15 || scores != 3 {
+			t.Fatalf("ticking every %v scored %v in %d messages over 3s, want 15 in 3", tick, score, scores)
+		}
+	}
+}
+
+func TestZoneControlOnlyNamesTheHoldersInSight(t *testing.T) {
+	h, clock, koth := newKothHub(t, RoomConfig{ID: "koth", VisibilityRadius: 3})
+	near, far := addClient(h, "near"), addClient(h, "far")
+	placePlayer(h, "holder", 1, 1)
+	placePlayer(h, "near", 3, 1)
+	placePlayer(h, "far", 14, 14)
+
+	koth.OnTick(h, clock.Now())
+	h.flushOutbox()
+	for client, want := range map[*Client]int{near: 1, far: 0} {
+		holders := -1
+		for len(client.Send) > 0 {
+			for _, event := range (<-client.Send).Content.(*models.StateUpdate).Events {
+				if control, ok := event.Content.(*models.ZoneControl); ok {
+					holders = len(control.Holders)
+				}
+			}
+		}
+		if holders != want {
+			t.Fatalf("%s was told about %d holders, want %d", client.ID, holders, want)
+		}
+	}
+}
+
+func TestZoneControlDoesNotGiveAwayHiddenHolders(t *testing.T) {
+	h, _, koth := newKothHub(t, RoomConfig{ID: "koth", VisibilityRadius: 3, Teams: 2})
+	h.teams = map[string]string{"red": "red", "blue": "blue", "watcher": "green"}
+	placePlayer(h, "red", 0, 2)
+	placePlayer(h, "blue", 2, 0)
+	placePlayer(h, "watcher", 0, 4)
+	koth.size = 3
+	koth.control = koth.zoneControl(h)
+	if !koth.control.Contested {
+		t.Fatal("two teams in the zone don't contest it")
+	}
+
+	seen := koth.controlSeenBy(h, "watcher", h.sightOf("watcher"))
+	if len(seen.Holders) != 1 || seen.Team != "red" || seen.Contested {
+		t.Fatalf("watcher sees %+v, want red holding the zone alone", seen)
+	}
+}
@@ -0,0 +1,54 @@
+package game
+
+import (
+	"math/rand"
+	"sync"
+)
+
+// lockedRand is a seeded random source that is safe to share between the
+// round, the Run loop and the joining clients.
+type lockedRand struct {
+	r  *rand.Rand
+	mu sync.Mutex
+}
+
+func newLockedRand(seed int64) *lockedRand {
+	return &lockedRand{r: rand.New(rand.NewSource(seed))}
+}
+
+// Seed restarts the sequence from seed.
+func (l *lockedRand) Seed(seed int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.r.Seed(seed)
+}
+
+func (l *lockedRand) Int63() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.r.Int63()
+}
+
+func (l *lockedRand) Float64() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.r.Float64()
+}
+
+func (l *lockedRand) Intn(n int) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.r.Intn(n)
+}
+
+func (l *lockedRand) Perm(n int) []int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.r.Perm(n)
+}
+
+func (l *lockedRand) Shuffle(n int, swap func(i, j int)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.r.Shuffle(n, swap)
+}
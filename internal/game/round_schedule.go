@@ -0,0 +1,160 @@
+package game
+
+import (
+	"pickup/internal/global"
+	"sync"
+	"time"
+)
+
+// phaseSpec is one entry of the round cycle, kept in the order the phases run.
+type phaseSpec struct {
+	State    string
+	Duration time.Duration
+}
+
+// RoundPhase is a concrete occurrence of a phase on the timeline.
+type RoundPhase struct {
+	State string
+	Start time.Time
+	End   time.Time
+}
+
+// RoundSchedule drives the round cycle: the state changes, the roundState end
+// times, the countdown and the next round start are all derived from it.
+type RoundSchedule struct {
+	phases []phaseSpec
+	origin time.Time // start of a cycle, every cycle repeats from here
+	mu     sync.RWMutex
+}
+
+var defaultPhaseDurations = []struct {
+	State     string
+	ConfigKey string
+	Seconds   int
+}{
+	{"waiting", "ROUND_WAITING_SEC", 5},
+	{"cleanup", "ROUND_CLEANUP_SEC", 2},
+	{"lobby", "LOBBY_TIMEOUT_SEC", 30},
+	{"preparing", "ROUND_PREPARING_SEC", 3},
+	{"playing", "ROUND_PLAYING_SEC", 49},
+	{"ended", "ROUND_ENDED_SEC", 1},
+}
+
+// NewRoundScheduleFromConfig builds a schedule whose first cycle starts at
+// start, the ready-check lobby only runs in rooms that enable it.
+func NewRoundScheduleFromConfig(start time.Time, withLobby bool) *RoundSchedule {
+	phases := make([]phaseSpec, 0, len(defaultPhaseDurations))
+	for _, d := range defaultPhaseDurations {
+		if d.State == "lobby" && !withLobby {
+			continue
+		}
+		seconds := d.Seconds
+		if global.Dv.IsSet(d.ConfigKey) {
+			seconds = global.Dv.GetInt(d.ConfigKey)
+		}
+		if seconds <= 0 {
+			seconds = d.Seconds
+		}
+		phases = append(phases, phaseSpec{State: d.State, Duration: time.Duration(seconds) * time.Second})
+	}
+
+	return &RoundSchedule{
+		phases: phases,
+		origin: start,
+	}
+}
+
+func (s *RoundSchedule) Length() time.Duration {
+	var total time.Duration
+	for _, p := range s.phases {
+		total += p.Duration
+	}
+	return total
+}
+
+func (s *RoundSchedule) cycleStart(now time.Time) time.Time {
+	length := s.Length()
+	elapsed := now.Sub(s.origin) % length
+	if elapsed < 0 {
+		elapsed += length
+	}
+	return now.Add(-elapsed)
+}
+
+// PhaseAt returns the phase running at the given time.
+func (s *RoundSchedule) PhaseAt(now time.Time) RoundPhase {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	start := s.cycleStart(now)
+	for _, p := range s.phases {
+		end := start.Add(p.Duration)
+		if now.Before(end) {
+			return RoundPhase{State: p.State, Start: start, End: end}
+		}
+		start = end
+	}
+
+	// unreachable while the cycle length is positive
+	last := s.phases[len(s.phases)-1]
+	return RoundPhase{State: last.State, Start: start.Add(-last.Duration), End: start}
+}
+
+// Restart moves the beginning of the cycle to start.
+func (s *RoundSchedule) Restart(start time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.origin = start
+}
+
+// StartPhaseAt shifts the cycle so that the given phase begins at t, the
+// phases after it keep their durations.
+func (s *RoundSchedule) StartPhaseAt(state string, t time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var offset time.Duration
+	for _, p := range s.phases {
+		if p.State == state {
+			s.origin = t.Add(-offset)
+			return
+		}
+		offset += p.Duration
+	}
+}
+
+// EndPhaseAt shifts the cycle so that the given phase ends at t, which
+// lengthens or shortens the running phase.
+func (s *RoundSchedule) EndPhaseAt(state string, t time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var offset time.Duration
+	for _, p := range s.phases {
+		offset += p.Duration
+		if p.State == state {
+			s.origin = t.Add(-offset)
+			return
+		}
+	}
+}
+
+// NextRoundStart returns the start of the next cycle, which opens with "waiting".
+func (s *RoundSchedule) NextRoundStart(now time.Time) time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cycleStart(now).Add(s.Length())
+}
+
+// PhaseDuration returns the configured length of a phase, or zero if the
+// schedule has no such phase.
+func (s *RoundSchedule) PhaseDuration(state string) time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, p := range s.phases {
+		if p.State == state {
+			return p.Duration
+		}
+	}
+	return 0
+}
@@ -0,0 +1,22 @@
+package initial
+
+import (
+	"go.uber.org/zap"
+	"pickup/internal/game"
+)
+
+func InitItemCatalogue() {
+	catalogue, err := game.LoadItemCatalogue()
+	if err != nil {
+		zap.S().Fatalf("error loading item catalogue: %v", err)
+	}
+	game.Catalogue = catalogue
+}
+
+func InitObstacleTypes() {
+	types, err := game.LoadObstacleTypes()
+	if err != nil {
+		zap.S().Fatalf("error loading obstacle types: %v", err)
+	}
+	game.ObstacleTypes = types
+}
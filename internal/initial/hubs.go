@@ -1,7 +1,9 @@
 package initial
 
 import (
+	"go.uber.org/zap"
 	"pickup/internal/game"
+	"pickup/internal/global"
 	"sync"
 )
 
@@ -11,14 +13,20 @@ func InitHubManager() {
 		Mu:   sync.RWMutex{},
 	}
 
-	h1 := game.NewHub(hm, "A")
-	h2 := game.NewHub(hm, "B")
-
-	h1.InitAllItems()
-	h2.InitAllItems()
+	var rooms []game.RoomConfig
+	if err := global.Dv.UnmarshalKey("ROOMS", &rooms); err != nil {
+		zap.S().Fatalf("error reading rooms config: %v", err)
+	}
 
-	hm.RegisterHub(h1)
-	hm.RegisterHub(h2)
+	for _, room := range rooms {
+		h, err := game.NewHub(hm, room)
+		if err != nil {
+			zap.S().Fatalf("error creating room %s: %v", room.ID, err)
+		}
+		h.InitAllItems()
+		hm.RegisterHub(h)
+		zap.S().Infof("room %s created with %s mode", h.ID, h.Mode.Name())
+	}
 
 	game.Hm = hm
 }
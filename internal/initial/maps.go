@@ -0,0 +1,15 @@
+package initial
+
+import (
+	"go.uber.org/zap"
+	"pickup/internal/game"
+	"pickup/internal/global"
+)
+
+func InitMaps() {
+	maps, err := game.LoadMaps(global.Dv.GetString("MAP_DIR"))
+	if err != nil {
+		zap.S().Fatalf("error loading maps: %v", err)
+	}
+	game.Maps = maps
+}
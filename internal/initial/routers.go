@@ -33,5 +33,10 @@ func InitRouters() *gin.Engine {
 	routers.InitConfigRouter(ApiGroup)
 	routers.InitUserRouter(ApiGroup)
 
+	// round seeds can be read and replaced by anyone, dev only
+	if global.Dv.GetBool("DEBUG_API") {
+		routers.InitDebugRouter(ApiGroup)
+	}
+
 	return r
 }
@@ -0,0 +1,17 @@
+package routers
+
+import (
+	"github.com/gin-gonic/gin"
+	"pickup/internal/api"
+)
+
+// InitDebugRouter adds the /debug endpoints. They are for development only:
+// nothing checks who calls them, so anyone reaching the server could pick the
+// map of the next round. Only the dev config turns them on with DEBUG_API.
+func InitDebugRouter(router *gin.RouterGroup) {
+	{
+		Router := router.Group("/debug")
+		Router.GET("/seed", api.GetRoundSeed)
+		Router.POST("/seed", api.SetNextRoundSeed)
+	}
+}
@@ -3,7 +3,10 @@ import {
     addObstacle,
     handleItemCollected,
     handleMoveResponse,
+    handleObstacleDamaged,
+    handleObstacleRemoved,
     notifyUser,
+    removeItem,
     sendMoveRequest,
     updateObstacleOnBoard,
     updatePlayerInList,
@@ -11,34 +14,104 @@ import {
 
 import {
     handleKeyPress,
+    handlePlayerGate,
+    handleReadyState,
+    handleRoundResult,
     handleRoundState,
     handleWaitingNotification,
     updateCountdown,
     updateTopPlayerOnScoreChange,
 } from "./game_round.js"
 
+import {
+    handleBombExploded,
+    handleBombPlaced,
+    handlePlayerEliminated,
+} from "./game_bomb.js";
+
+import {
+    handleEffectApplied,
+    handleEffectExpired,
+    handleHazardTriggered,
+} from "./game_effect.js";
+
+import {
+    handleEntityHit,
+    handleEntityPosition,
+} from "./game_entity.js";
+
+import {
+    handleEnterView,
+    handleLeaveView,
+    updateFog,
+} from "./game_view.js";
+
+import {
+    handleTeamState,
+    updateTeamScore,
+} from "./game_team.js";
+
+import {
+    handleBase,
+    handleFlagState,
+} from "./game_ctf.js";
+
+import {
+    handleZoneControl,
+    handleZonePosition,
+} from "./game_zone.js";
+
 import {shared_state} from "./game_shared.js";
 
 document.addEventListener('DOMContentLoaded', async () => {
     const messageHandlers = {
         obstaclePosition: addObstacle,
+        obstacleDamaged: handleObstacleDamaged,
+        obstacleRemoved: handleObstacleRemoved,
         playerPosition: handleMoveResponse,
         itemPosition: addItem,
         itemCollected: handleItemCollected,
+        itemExpired: removeItem,
+        hazardTriggered: handleHazardTriggered,
         errorMsg: (content) => notifyUser("Error: " + content.error),
         score: updateSingleScore,
         countdown: updateCountdown,
         roundState: handleRoundState,
+        roundResult: handleRoundResult,
         waitingNotification: handleWaitingNotification,
+        waitingForPlayers: handlePlayerGate,
+        readyState: handleReadyState,
+        roomInfo: handleRoomInfo,
+        bombPlaced: handleBombPlaced,
+        bombExploded: handleBombExploded,
+        playerEliminated: handlePlayerEliminated,
+        effectApplied: handleEffectApplied,
+        effectExpired: handleEffectExpired,
+        entityPosition: handleEntityPosition,
+        entityHit: handleEntityHit,
+        enterView: (view) => handleEnterView(view, dispatchMessage),
+        leaveView: handleLeaveView,
+        teamState: handleTeamState,
+        base: handleBase,
+        flagState: handleFlagState,
+        zonePosition: handleZonePosition,
+        zoneControl: handleZoneControl,
+        stateUpdate: (update) => update.events.forEach(dispatchMessage),
     };
 
+    function dispatchMessage(data) {
+        const handler = messageHandlers[data.type];
+        if (handler) handler(data.content);
+        else console.warn('Unhandled message type:', data.type);
+    }
+
     initializeDOMReferences()
 
     try {
         const config = await fetchConfig();
         if (!config) throw new Error('Failed to load configuration');
 
-        shared_state.gridSize = config.gridsize || shared_state.gridSize;
+        shared_state.itemCatalogue = config.items || [];
         shared_state.playerId = await getUserId();
         if (!shared_state.playerId) throw new Error('Failed to get user ID');
 
@@ -133,10 +206,7 @@ document.addEventListener('DOMContentLoaded', async () => {
         };
 
         shared_state.socket.onmessage = (event) => {
-            const data = JSON.parse(event.data);
-            const handler = messageHandlers[data.type];
-            if (handler) handler(data.content);
-            else console.warn('Unhandled message type:', data.type);
+            dispatchMessage(JSON.parse(event.data));
         };
 
         shared_state.socket.onclose = () => console.log("WebSocket closed");
@@ -159,16 +229,38 @@ document.addEventListener('DOMContentLoaded', async () => {
     }
 
     function createGameBoard() {
-        shared_state.gameBoard.innerHTML = Array(shared_state.gridSize).fill().map((_, y) =>
-            Array(shared_state.gridSize).fill().map((_, x) =>
+        const board = shared_state.gameBoard;
+        board.style.setProperty('--grid-width', shared_state.gridWidth);
+        board.style.setProperty('--grid-height', shared_state.gridHeight);
+        // large arenas get smaller cells to stay on screen
+        const cellSize = Math.max(18, Math.min(45, Math.floor(900 / Math.max(shared_state.gridWidth, shared_state.gridHeight))));
+        board.style.setProperty('--cell-size', `${cellSize}px`);
+
+        board.innerHTML = Array(shared_state.gridHeight).fill().map((_, y) =>
+            Array(shared_state.gridWidth).fill().map((_, x) =>
                 `<div class="cell" id="cell-${x}-${y}"></div>`
             ).join('')
         ).join('');
     }
 
 
+    function handleRoomInfo(roomInfo) {
+        shared_state.mode = roomInfo.mode;
+        shared_state.visibilityRadius = roomInfo.visibilityRadius || 0;
+        // the board size comes with the state sync, maps can change it every round
+        if (roomInfo.width !== shared_state.gridWidth || roomInfo.height !== shared_state.gridHeight) {
+            shared_state.gridWidth = roomInfo.width;
+            shared_state.gridHeight = roomInfo.height;
+            createGameBoard();
+        }
+        updateFog();
+    }
+
     function updateSingleScore(scoreUpdate) {
         shared_state.playerScores[scoreUpdate.id] = scoreUpdate.score;
+        if (scoreUpdate.team) {
+            updateTeamScore(scoreUpdate.team, scoreUpdate.teamScore || 0);
+        }
         updatePlayerInList(scoreUpdate.id);
         // for the showWaitingOverlay
         updateTopPlayerOnScoreChange()
@@ -1,4 +1,7 @@
 import { shared_state } from "./game_shared.js";
+import {effectLabel, immobilized, maxSteps, updateEffectOnBoard} from "./game_effect.js";
+import {updateFog} from "./game_view.js";
+import {teamLabel} from "./game_team.js";
 
 export function updatePlayerPosition(playerData, status = 'confirmed') {
     if (!playerData?.position) {
@@ -8,7 +11,7 @@ export function updatePlayerPosition(playerData, status = 'confirmed') {
 
     const oldCell = document.querySelector(`.player[data-player-id="${playerData.id}"]`);
     if (oldCell) {
-        oldCell.classList.remove('player', 'current-player', 'other-player', 'unconfirmed');
+        oldCell.classList.remove('player', 'current-player', 'other-player', 'unconfirmed', 'frozen', 'shielded', 'phasing', 'cursed');
         oldCell.removeAttribute('data-player-id');
     }
 
@@ -33,11 +36,16 @@ export function updatePlayerPosition(playerData, status = 'confirmed') {
     cell.classList.toggle('player-on-coin', cell.classList.contains('coin'));
 
     shared_state.players[playerData.id] = playerData.position;
+    updateEffectOnBoard(playerData.id);
     updatePlayerInList(playerData.id);
 }
 
 export function sendMoveRequest(direction) {
     if (shared_state.socket?.readyState === WebSocket.OPEN) {
+        if (direction !== 'initial' && immobilized(shared_state.playerId)) {
+            notifyUser("You can't move right now");
+            return;
+        }
         const newPosition = direction === 'initial' ? shared_state.playerPosition : calculateNewPosition(shared_state.playerPosition, direction);
         if (isValidMove(shared_state.playerPosition, newPosition)) {
             updatePlayerPosition({id: shared_state.playerId, position: newPosition}, 'unconfirmed');
@@ -55,27 +63,29 @@ export function sendMoveRequest(direction) {
 
 export function calculateNewPosition(currentPosition, direction) {
     const newPosition = {...currentPosition};
+    const steps = maxSteps(shared_state.playerId);
     switch (direction) {
         case 'up':
-            newPosition.y = Math.max(0, newPosition.y - 1);
+            newPosition.y = Math.max(0, newPosition.y - steps);
             break;
         case 'down':
-            newPosition.y = Math.min(shared_state.gridSize - 1, newPosition.y + 1);
+            newPosition.y = Math.min(shared_state.gridHeight - 1, newPosition.y + steps);
             break;
         case 'left':
-            newPosition.x = Math.max(0, newPosition.x - 1);
+            newPosition.x = Math.max(0, newPosition.x - steps);
             break;
         case 'right':
-            newPosition.x = Math.min(shared_state.gridSize - 1, newPosition.x + 1);
+            newPosition.x = Math.min(shared_state.gridWidth - 1, newPosition.x + steps);
             break;
     }
     return newPosition;
 }
 
 export function isValidMove(currentPosition, newPosition) {
-    return newPosition.x >= 0 && newPosition.x < shared_state.gridSize &&
-        newPosition.y >= 0 && newPosition.y < shared_state.gridSize &&
-        (Math.abs(newPosition.x - currentPosition.x) + Math.abs(newPosition.y - currentPosition.y) === 1);
+    const distance = Math.abs(newPosition.x - currentPosition.x) + Math.abs(newPosition.y - currentPosition.y);
+    return newPosition.x >= 0 && newPosition.x < shared_state.gridWidth &&
+        newPosition.y >= 0 && newPosition.y < shared_state.gridHeight &&
+        distance >= 1 && distance <= maxSteps(shared_state.playerId);
 }
 
 export function updatePlayerInList(userId) {
@@ -90,12 +100,21 @@ export function updatePlayerInList(userId) {
     const isCurrentPlayer = userId === shared_state.playerId;
 
     playerElement.className = `player-item${isCurrentPlayer ? ' current-player' : ''}`;
-    playerElement.textContent = `Player ${userId}: Score ${score}${isCurrentPlayer ? ' (You)' : ''}`;
+    playerElement.textContent = `Player ${userId} ${teamLabel(userId)}: Score ${score}${isCurrentPlayer ? ' (You)' : ''} ${effectLabel(userId)}`.replace(' :', ':').trim();
 
 }
 
 export function sendItemActionRequest() {
     if (shared_state.socket?.readyState === WebSocket.OPEN) {
+        // in bomberman the action key drops a bomb anywhere, in ctf the server looks for a flag
+        if (shared_state.mode === 'bomberman' || shared_state.mode === 'ctf') {
+            shared_state.socket.send(JSON.stringify({
+                type: 'itemAction',
+                content: {id: shared_state.playerId, position: shared_state.playerPosition}
+            }));
+            return;
+        }
+
         const itemAtPosition = shared_state.items.find(item =>
             item.position.x === shared_state.playerPosition.x && item.position.y === shared_state.playerPosition.y
         );
@@ -128,21 +147,20 @@ export function handleMoveResponse(response) {
             notifyUser("Invalid move: " + (response.reason || "Unknown reason"));
         }
         updatePlayerPosition({id: shared_state.playerId, position: shared_state.playerPosition});
+        updateFog();
     } else {
         updatePlayerPosition(response);
     }
 }
 
-const itemHandlers = {
-    coin: () => console.log('Coin collected'),
-    diamond: () => console.log('Diamond collected')
-};
+// item types with their own icon in game.css, other catalogue items get a label
+const styledItemTypes = ['coin', 'diamond', 'speed', 'freeze', 'shield', 'phase', 'trap', 'stun', 'cursed'];
 
 export function handleItemCollected(data) {
     if (data.valid) {
         removeItem(data);
-        const handler = itemHandlers[data.item.type];
-        if (handler) handler();
+        const definition = shared_state.itemCatalogue.find(def => def.type === data.item.type);
+        console.log(`${data.item.type} collected` + (definition?.kind === 'effect' ? ` (${definition.effect})` : ''));
     } else {
         notifyUser("Failed to collect item: " + data.reason);
     }
@@ -154,7 +172,8 @@ export function removeItem(item) {
         const removedItem = shared_state.items.splice(index, 1)[0];
         const cell = document.getElementById(`cell-${item.position.x}-${item.position.y}`);
         if (cell) {
-            cell.classList.remove('item', `item-${removedItem.item.type}`, 'player-on-item');
+            cell.classList.remove('item', `item-${removedItem.item.type}`, 'item-generic', 'player-on-item');
+            cell.removeAttribute('data-item-label');
         }
     }
 }
@@ -167,14 +186,57 @@ export function addObstacle(obstacle) {
 export function updateObstacleOnBoard(obstacle) {
     const cell = document.getElementById(`cell-${obstacle.x}-${obstacle.y}`);
     if (cell) {
-        cell.classList.add('obstacle');
+        cell.classList.add('obstacle', `obstacle-${obstacle.type}`);
+        if (obstacle.maxHp > 0) {
+            cell.setAttribute('data-hp', obstacle.hp);
+        }
     }
 }
 
+export function handleObstacleDamaged(hit) {
+    const obstacle = shared_state.obstacles.find(o => o.x === hit.obstacle.x && o.y === hit.obstacle.y);
+    if (obstacle) {
+        obstacle.hp = hit.obstacle.hp;
+    }
+    updateObstacleOnBoard(hit.obstacle);
+}
+
+export function handleObstacleRemoved(hit) {
+    const {x, y, type} = hit.obstacle;
+    shared_state.obstacles = shared_state.obstacles.filter(o => o.x !== x || o.y !== y);
+    const cell = document.getElementById(`cell-${x}-${y}`);
+    if (cell) {
+        cell.classList.remove('obstacle', `obstacle-${type}`);
+        cell.removeAttribute('data-hp');
+    }
+}
+
+const attackOffsets = {
+    up: {x: 0, y: -1},
+    down: {x: 0, y: 1},
+    left: {x: -1, y: 0},
+    right: {x: 1, y: 0},
+};
+
+// sendAttackRequest attacks the cell next to the player in a direction
+export function sendAttackRequest(direction) {
+    const offset = attackOffsets[direction];
+    if (!offset || shared_state.socket?.readyState !== WebSocket.OPEN) return;
+    const target = {x: shared_state.playerPosition.x + offset.x, y: shared_state.playerPosition.y + offset.y};
+    shared_state.socket.send(JSON.stringify({
+        type: 'attack',
+        content: {id: shared_state.playerId, position: target}
+    }));
+}
+
 export function updateItemOnBoard(item) {
     const cell = document.getElementById(`cell-${item.position.x}-${item.position.y}`);
     if (cell) {
         cell.classList.add('item', `item-${item.item.type}`);
+        if (!styledItemTypes.includes(item.item.type)) {
+            cell.classList.add('item-generic');
+            cell.setAttribute('data-item-label', item.item.type.charAt(0).toUpperCase());
+        }
     }
 }
 
@@ -0,0 +1,52 @@
+import {shared_state} from "./game_shared.js";
+import {notifyUser} from "./game_action.js";
+
+export function handleBombPlaced(bomb) {
+    shared_state.bombs.push(bomb);
+    const cell = document.getElementById(`cell-${bomb.position.x}-${bomb.position.y}`);
+    if (cell) {
+        cell.classList.add('bomb');
+    }
+}
+
+export function handleBombExploded(explosion) {
+    shared_state.bombs = shared_state.bombs.filter(bomb =>
+        bomb.position.x !== explosion.position.x || bomb.position.y !== explosion.position.y
+    );
+    const bombCell = document.getElementById(`cell-${explosion.position.x}-${explosion.position.y}`);
+    if (bombCell) {
+        bombCell.classList.remove('bomb');
+    }
+
+    explosion.destroyed.forEach(position => {
+        shared_state.obstacles = shared_state.obstacles.filter(o => o.x !== position.x || o.y !== position.y);
+        const cell = document.getElementById(`cell-${position.x}-${position.y}`);
+        if (cell) {
+            cell.classList.remove('obstacle', ...Array.from(cell.classList).filter(c => c.startsWith('obstacle-')));
+            cell.removeAttribute('data-hp');
+        }
+    });
+
+    explosion.cells.forEach(position => {
+        const cell = document.getElementById(`cell-${position.x}-${position.y}`);
+        if (cell) {
+            cell.classList.add('explosion');
+            setTimeout(() => cell.classList.remove('explosion'), 400);
+        }
+    });
+}
+
+export function handlePlayerEliminated(elimination) {
+    const cell = document.querySelector(`.player[data-player-id="${elimination.id}"]`);
+    if (cell) {
+        cell.classList.remove('player', 'current-player', 'other-player', 'unconfirmed');
+        cell.removeAttribute('data-player-id');
+    }
+    delete shared_state.players[elimination.id];
+
+    if (elimination.id === shared_state.playerId) {
+        notifyUser(`You were eliminated by ${elimination.by}`);
+    } else {
+        notifyUser(`Player ${elimination.id} was eliminated by ${elimination.by}`);
+    }
+}
@@ -0,0 +1,48 @@
+import {shared_state} from "./game_shared.js";
+import {notifyUser} from "./game_action.js";
+
+export function handleBase(base) {
+    base.cells.forEach(position => {
+        const cell = document.getElementById(`cell-${position.x}-${position.y}`);
+        if (cell) cell.classList.add('base', `base-${base.team}`);
+    });
+}
+
+export function handleFlagState(flag) {
+    removeFlag(flag.team);
+    shared_state.flags[flag.team] = flag;
+
+    const cell = document.getElementById(`cell-${flag.position.x}-${flag.position.y}`);
+    if (cell) {
+        cell.classList.add('flag', `flag-${flag.team}`);
+        cell.classList.toggle('flag-carried', !!flag.carrier);
+    }
+
+    const by = flag.by === shared_state.playerId ? 'You' : `Player ${flag.by}`;
+    switch (flag.event) {
+        case 'taken':
+            // sent on every step of the carrier
+            if (flag.by && flag.by === flag.carrier && shared_state.flagCarriers[flag.team] !== flag.carrier) {
+                notifyUser(`${by} took the ${flag.team} flag`);
+            }
+            break;
+        case 'dropped':
+            notifyUser(`The ${flag.team} flag was dropped, it goes home in ${Math.round((flag.returnAt - Date.now()) / 1000)}s`);
+            break;
+        case 'returned':
+            notifyUser(`The ${flag.team} flag is back home`);
+            break;
+        case 'captured':
+            notifyUser(`${by} captured the ${flag.team} flag`);
+            break;
+    }
+    shared_state.flagCarriers[flag.team] = flag.carrier || null;
+}
+
+function removeFlag(team) {
+    const previous = shared_state.flags[team];
+    if (!previous) return;
+    const oldCell = document.getElementById(`cell-${previous.position.x}-${previous.position.y}`);
+    if (oldCell) oldCell.classList.remove('flag', `flag-${team}`, 'flag-carried');
+    delete shared_state.flags[team];
+}
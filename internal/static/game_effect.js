@@ -0,0 +1,62 @@
+import {shared_state} from "./game_shared.js";
+import {notifyUser, removeItem, updatePlayerInList} from "./game_action.js";
+
+const effectIcons = {
+    speed: '⚡',
+    frozen: '❄️',
+    shield: '🛡️',
+    phase: '👻',
+    stunned: '💫',
+    cursed: '💀',
+};
+
+export function handleEffectApplied(action) {
+    shared_state.effects[action.id] = {...shared_state.effects[action.id], [action.effect]: action.expiresAt};
+    if (action.id === shared_state.playerId) {
+        notifyUser(`You got ${action.effect}` + (action.source && action.source !== action.id ? ` from ${action.source}` : ''));
+    }
+    updateEffectOnBoard(action.id);
+    updatePlayerInList(action.id);
+}
+
+export function handleHazardTriggered(data) {
+    removeItem(data);
+    if (data.id === shared_state.playerId) {
+        notifyUser(`You stepped on a ${data.item.type}`);
+    }
+}
+
+export function handleEffectExpired(action) {
+    if (shared_state.effects[action.id]) {
+        delete shared_state.effects[action.id][action.effect];
+    }
+    updateEffectOnBoard(action.id);
+    updatePlayerInList(action.id);
+}
+
+export function hasEffect(playerId, effect) {
+    return Boolean(shared_state.effects[playerId]?.[effect]);
+}
+
+// immobilized mirrors the server: frozen and stunned players can't move
+export function immobilized(playerId) {
+    return hasEffect(playerId, 'frozen') || hasEffect(playerId, 'stunned');
+}
+
+// maxSteps mirrors the server: a speed boost allows two steps per move
+export function maxSteps(playerId) {
+    return hasEffect(playerId, 'speed') ? 2 : 1;
+}
+
+export function effectLabel(playerId) {
+    return Object.keys(shared_state.effects[playerId] || {}).map(effect => effectIcons[effect] || effect).join('');
+}
+
+export function updateEffectOnBoard(playerId) {
+    document.querySelectorAll(`.cell[data-player-id="${playerId}"]`).forEach(cell => {
+        cell.classList.toggle('frozen', immobilized(playerId));
+        cell.classList.toggle('cursed', hasEffect(playerId, 'cursed'));
+        cell.classList.toggle('shielded', hasEffect(playerId, 'shield'));
+        cell.classList.toggle('phasing', hasEffect(playerId, 'phase'));
+    });
+}
@@ -0,0 +1,33 @@
+import {shared_state} from "./game_shared.js";
+import {notifyUser} from "./game_action.js";
+
+export function handleEntityPosition(entity) {
+    removeEntity(entity.id);
+    shared_state.entities[entity.id] = entity;
+
+    const cell = document.getElementById(`cell-${entity.position.x}-${entity.position.y}`);
+    if (cell) {
+        cell.classList.add('entity', `entity-${entity.kind}`);
+        cell.setAttribute('data-entity-id', entity.id);
+    }
+}
+
+export function removeEntity(entityId) {
+    const previous = shared_state.entities[entityId];
+    if (!previous) return;
+    const oldCell = document.getElementById(`cell-${previous.position.x}-${previous.position.y}`);
+    if (oldCell) {
+        oldCell.classList.remove('entity', `entity-${previous.kind}`);
+        oldCell.removeAttribute('data-entity-id');
+    }
+    delete shared_state.entities[entityId];
+}
+
+export function handleEntityHit(hit) {
+    const penalty = hit.points ? `, -${hit.points} points` : '';
+    if (hit.id === shared_state.playerId) {
+        notifyUser(`Caught by ${hit.by}${penalty}${hit.knockedOut ? ', back to the start' : ''}`);
+    } else {
+        notifyUser(`Player ${hit.id} was caught by ${hit.by}`);
+    }
+}
@@ -1,5 +1,14 @@
 import {shared_state} from "./game_shared.js";
-import {sendItemActionRequest, sendMoveRequest, updatePlayerInList} from "./game_action.js";
+import {notifyUser, sendAttackRequest, sendItemActionRequest, sendMoveRequest, updatePlayerInList} from "./game_action.js";
+import {renderTeamScores, teamPicker, updateTeamScore} from "./game_team.js";
+
+const roundEndReasons = {
+    timeUp: 'Time is up',
+    scoreTarget: 'Score target reached',
+    allItemsCollected: 'All items collected',
+    lastPlayerStanding: 'Last player standing',
+    suddenDeath: 'Sudden death decided',
+};
 
 export function handleRoundState(roundState) {
     const state = roundState.state;
@@ -11,21 +20,38 @@ export function handleRoundState(roundState) {
     removeWaitingOverlay();
 
     if (state === 'playing') {
+        shared_state.roundResult = null;
         shared_state.playerScores = {};
         Object.keys(shared_state.players).forEach(playerId => {
             shared_state.playerScores[playerId] = 0;
         });
         shared_state.playerScores[shared_state.playerId] = 0;
+        shared_state.teamScores = {};
+        renderTeamScores();
         updateAllPlayerScores();
         removeWaitingOverlay();
         resumeGame();
+    } else if (state === 'lobby') {
+        pauseGame();
+        shared_state.isReady = false;
+        showLobbyOverlay();
+    } else if (state === 'overtime') {
+        removeWaitingOverlay();
+        resumeGame();
+        notifyUser(`Overtime! Only ${roundState.players.join(', ')} can score`);
     } else if (state === 'waiting') {
         pauseGame();
         showWaitingOverlay(`Waiting for the next round. \nProcessing: ${state}`, true);
-    } else if (state === 'preparing' || state === 'ended') {
+    } else if (state === 'preparing') {
         pauseGame();
+        // quote it in bug reports, the round can be replayed from it
+        console.log(`Round seed: ${roundState.seed}`);
         updateTopPlayerOnScoreChange()
         showWaitingOverlay(`Waiting for the next round. \nProcessing: ${state}`);
+    } else if (state === 'ended') {
+        pauseGame();
+        updateTopPlayerOnScoreChange()
+        showWaitingOverlay(`${roundEndReasons[roundState.reason] || 'Round ended'}. \nWaiting for the next round.`);
     }
 
     if (state === 'cleanup') {
@@ -41,6 +67,16 @@ export function handleRoundState(roundState) {
     });
 }
 
+export function handleRoundResult(result) {
+    shared_state.roundResult = result;
+    result.rankings.forEach(player => {
+        shared_state.playerScores[player.id] = player.score;
+    });
+    (result.teams || []).forEach(team => updateTeamScore(team.team, team.score));
+    updateAllPlayerScores();
+    updateTopPlayerOnScoreChange();
+}
+
 export function updateAllPlayerScores() {
     Object.keys(shared_state.players).forEach(playerId => {
         updatePlayerInList(playerId);
@@ -53,12 +89,23 @@ export function resetGameData() {
     shared_state.players = {};
     shared_state.obstacles = [];
     shared_state.items = [];
+    shared_state.bombs = [];
+    shared_state.effects = {};
+    shared_state.entities = {};
+    shared_state.flags = {};
+    shared_state.flagCarriers = {};
+    shared_state.zone = null;
+    shared_state.zoneControl = null;
 
     const gameBoard = document.getElementById('game-board');
     const cells = gameBoard.getElementsByClassName('cell');
     Array.from(cells).forEach(cell => {
-        cell.classList.remove('player', 'current-player', 'other-player', 'obstacle', 'item', 'item-coin', 'item-diamond', 'player-on-item');
+        // drop every player, item, obstacle, entity, flag, zone and effect class at once
+        cell.className = 'cell';
         cell.removeAttribute('data-player-id');
+        cell.removeAttribute('data-item-label');
+        cell.removeAttribute('data-hp');
+        cell.removeAttribute('data-entity-id');
     });
 
     const playerList = document.getElementById('player-list');
@@ -100,12 +147,18 @@ export function updateCountdown(countdownUpdate) {
         case 'cleanup':
             displayText = `Cleanup time: ${remainingTime}s`;
             break;
+        case 'lobby':
+            displayText = `Ready check: ${remainingTime}s`;
+            break;
         case 'preparing':
             displayText = `Preparing time: ${remainingTime}s`;
             break;
         case 'playing':
             displayText = `Remaining time: ${remainingTime}s`;
             break;
+        case 'overtime':
+            displayText = `Overtime: ${remainingTime}s`;
+            break;
         case 'ended':
             displayText = 'Game Ended';
             break;
@@ -207,13 +260,68 @@ const directionMap = {
 
 export function handleKeyPress(event) {
     const direction = directionMap[event.key];
-    if (direction) {
+    if (direction && event.shiftKey) {
+        sendAttackRequest(direction);
+    } else if (direction) {
         sendMoveRequest(direction);
     } else if (event.key === ' ') {
         sendItemActionRequest();
     }
 }
 
+export function handlePlayerGate(gate) {
+    pauseGame();
+    if (gate.needed > 0) {
+        showWaitingOverlay(`Waiting for ${gate.needed} more player${gate.needed > 1 ? 's' : ''} (${gate.connected}/${gate.required})`);
+    } else {
+        const seconds = Math.max(0, Math.round((gate.startAt - Date.now()) / 1000));
+        showWaitingOverlay(`Enough players joined, the round starts in ${seconds}s`);
+    }
+}
+
+export function showLobbyOverlay(readyState = null) {
+    removeWaitingOverlay();
+
+    const overlay = document.createElement('div');
+    overlay.id = 'waiting-overlay';
+    overlay.className = 'lobby-overlay';
+
+    const message = document.createElement('p');
+    if (readyState) {
+        message.textContent = `Ready: ${readyState.ready.length}/${readyState.players.length}`;
+    } else {
+        message.textContent = 'Ready check: press the button to join the next round';
+    }
+    overlay.appendChild(message);
+
+    const readyButton = document.createElement('button');
+    readyButton.className = 'ready-btn';
+    readyButton.textContent = shared_state.isReady ? 'Waiting for others...' : 'Ready';
+    readyButton.disabled = shared_state.isReady;
+    readyButton.addEventListener('click', sendPlayerReady);
+    overlay.appendChild(readyButton);
+
+    const picker = teamPicker();
+    if (picker) overlay.appendChild(picker);
+
+    document.body.appendChild(overlay);
+}
+
+export function handleReadyState(readyState) {
+    shared_state.isReady = readyState.ready.includes(shared_state.playerId);
+    shared_state.readyState = readyState;
+    showLobbyOverlay(readyState);
+}
+
+function sendPlayerReady() {
+    if (shared_state.socket?.readyState === WebSocket.OPEN && !shared_state.isReady) {
+        shared_state.socket.send(JSON.stringify({
+            type: 'playerReady',
+            content: {id: shared_state.playerId}
+        }));
+    }
+}
+
 export function handleWaitingNotification(content) {
     pauseGame();
     removeWaitingOverlay();
@@ -1,6 +1,9 @@
 export const shared_state = {
     // vars
-    gridSize: 15,
+    gridWidth: 15,
+    gridHeight: 15,
+    mode: 'classic',
+    visibilityRadius: 0,
     socket: null,
     playerPosition: {x: 0, y: 0},
     lastConfirmedPosition: {x: 0, y: 0},
@@ -9,6 +12,21 @@ export const shared_state = {
     playerScores: {},
     obstacles: [],
     items: [],
+    itemCatalogue: [],
+    bombs: [],
+    effects: {},
+    entities: {},
+    flags: {},
+    flagCarriers: {},
+    zone: null,
+    zoneControl: null,
+    teams: {},
+    teamNames: [],
+    teamScores: {},
+    teamAssignment: null,
+    readyState: null,
+    roundResult: null,
+    isReady: false,
     isGameInitialized: false,
 
     // DOM
@@ -18,6 +36,11 @@ export const shared_state = {
 
     // func
     getTopPlayer: function() {
+        // the server result is authoritative once the round has ended
+        if (this.roundResult) {
+            const winner = this.roundResult.rankings.find(p => this.roundResult.winners.includes(p.id));
+            return winner ? [this.roundResult.winners.join(', '), winner.score] : null;
+        }
         const entries = Object.entries(this.playerScores);
         if (entries.length === 0) return null;
         return entries.reduce((top, current) => current[1] > top[1] ? current : top);
@@ -0,0 +1,65 @@
+import {shared_state} from "./game_shared.js";
+import {updatePlayerInList} from "./game_action.js";
+import {showLobbyOverlay} from "./game_round.js";
+
+export function handleTeamState(state) {
+    shared_state.teamAssignment = state.assignment;
+    shared_state.teamNames = Object.keys(state.teams);
+    shared_state.teams = {};
+    Object.entries(state.teams).forEach(([team, members]) => {
+        members.forEach(userId => shared_state.teams[userId] = team);
+    });
+    Object.keys(shared_state.teams).forEach(updatePlayerInList);
+    renderTeamScores();
+    // picks arrive while the ready-check is open
+    if (document.querySelector('.lobby-overlay')) {
+        showLobbyOverlay(shared_state.readyState);
+    }
+}
+
+export function updateTeamScore(team, score) {
+    shared_state.teamScores[team] = score;
+    renderTeamScores();
+}
+
+export function teamLabel(userId) {
+    const team = shared_state.teams[userId];
+    return team ? `[${team}]` : '';
+}
+
+export function renderTeamScores() {
+    const board = document.getElementById('team-scores');
+    if (!board) return;
+    board.innerHTML = '';
+    shared_state.teamNames.forEach(team => {
+        const entry = document.createElement('div');
+        entry.className = `team-item team-${team}`;
+        const members = Object.values(shared_state.teams).filter(t => t === team).length;
+        entry.textContent = `Team ${team} (${members}): ${shared_state.teamScores[team] || 0}`;
+        board.appendChild(entry);
+    });
+}
+
+// team buttons of the lobby overlay, in rooms where the players pick their team
+export function teamPicker() {
+    if (shared_state.teamAssignment !== 'lobby') return null;
+    const picker = document.createElement('div');
+    picker.className = 'team-picker';
+    shared_state.teamNames.forEach(team => {
+        const button = document.createElement('button');
+        button.className = `team-btn team-${team}`;
+        button.textContent = shared_state.teams[shared_state.playerId] === team ? `${team} ✓` : team;
+        button.addEventListener('click', () => chooseTeam(team));
+        picker.appendChild(button);
+    });
+    return picker;
+}
+
+function chooseTeam(team) {
+    if (shared_state.socket?.readyState === WebSocket.OPEN) {
+        shared_state.socket.send(JSON.stringify({
+            type: 'chooseTeam',
+            content: {id: shared_state.playerId, team: team}
+        }));
+    }
+}
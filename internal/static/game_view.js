@@ -0,0 +1,45 @@
+import {shared_state} from "./game_shared.js";
+import {removeItem} from "./game_action.js";
+import {removeEntity} from "./game_entity.js";
+
+// fog of war: the server only sends what the player can see, enterView
+// carries the state of what came into view
+export function handleEnterView(view, dispatch) {
+    if (view.kind === 'item') {
+        removeItem(view.state.content);
+    }
+    dispatch(view.state);
+}
+
+export function handleLeaveView(view) {
+    switch (view.kind) {
+        case 'player': {
+            const cell = document.querySelector(`.player[data-player-id="${view.id}"]`);
+            if (cell) {
+                cell.classList.remove('player', 'other-player', 'frozen', 'shielded', 'phasing', 'cursed');
+                cell.removeAttribute('data-player-id');
+            }
+            delete shared_state.players[view.id];
+            break;
+        }
+        case 'entity':
+            removeEntity(view.id);
+            break;
+        case 'item':
+            removeItem(view);
+            break;
+    }
+}
+
+// updateFog shades the cells beyond the visibility radius, cells hidden by
+// obstacles are left to the server
+export function updateFog() {
+    const radius = shared_state.visibilityRadius;
+    const cells = shared_state.gameBoard.getElementsByClassName('cell');
+    Array.from(cells).forEach(cell => {
+        const [, x, y] = cell.id.split('-').map(Number);
+        const dx = x - shared_state.playerPosition.x;
+        const dy = y - shared_state.playerPosition.y;
+        cell.classList.toggle('fogged', radius > 0 && dx * dx + dy * dy > radius * radius);
+    });
+}
@@ -0,0 +1,39 @@
+import {shared_state} from "./game_shared.js";
+import {notifyUser} from "./game_action.js";
+
+export function handleZonePosition(zone) {
+    zoneCells().forEach(cell => cell.classList.remove('zone', 'zone-held', 'zone-contested'));
+    shared_state.zone = zone;
+    zoneCells().forEach(cell => cell.classList.add('zone'));
+    renderZoneControl();
+    if (zone.moveAt) {
+        console.log(`The zone moves again in ${Math.round((zone.moveAt - Date.now()) / 1000)}s`);
+    }
+}
+
+export function handleZoneControl(control) {
+    shared_state.zoneControl = control;
+    renderZoneControl();
+    if (control.contested) {
+        notifyUser('The zone is contested');
+    } else if (control.holders.includes(shared_state.playerId)) {
+        notifyUser('You hold the zone');
+    } else if (control.holders.length > 0) {
+        notifyUser(`${control.team ? `Team ${control.team}` : `Player ${control.holders[0]}`} holds the zone`);
+    }
+}
+
+function renderZoneControl() {
+    const control = shared_state.zoneControl;
+    zoneCells().forEach(cell => {
+        cell.classList.toggle('zone-contested', !!control?.contested);
+        cell.classList.toggle('zone-held', !!control && !control.contested && control.holders.length > 0);
+    });
+}
+
+function zoneCells() {
+    if (!shared_state.zone) return [];
+    return shared_state.zone.cells
+        .map(position => document.getElementById(`cell-${position.x}-${position.y}`))
+        .filter(cell => cell);
+}
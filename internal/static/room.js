@@ -13,7 +13,11 @@ function checkRoomStatus(room) {
             serverTimeDiff = data.serverTime ? currentTime - serverTime : 0;
             console.log(`Current time: ${currentTime}, Server time: ${serverTime}, Difference: ${serverTimeDiff}ms`);
 
-            updateRoomStatus(room, nextRoundStart, data.state);
+            if (data.playersNeeded > 0) {
+                updateRoomWaitingForPlayers(room, data.playersNeeded);
+            } else {
+                updateRoomStatus(room, nextRoundStart, data.state, data.joinWindow);
+            }
         })
         .catch(error => {
             console.error('Error:', error);
@@ -21,7 +25,7 @@ function checkRoomStatus(room) {
         });
 }
 
-function updateRoomStatus(room, nextRoundStart, state) {
+function updateRoomStatus(room, nextRoundStart, state, joinWindow = 50) {
     if (countdowns[room]) {
         clearInterval(countdowns[room]);
     }
@@ -36,7 +40,7 @@ function updateRoomStatus(room, nextRoundStart, state) {
         const remainingTime = Math.max(0, Math.floor((nextRoundStart - now) / 1000));
 
         let statusText, countdownText, canJoin;
-        let reversePreparingTime = 50
+        let reversePreparingTime = joinWindow
         if (remainingTime > reversePreparingTime) {
             statusText = "Game Preparing";
             countdownText = `${remainingTime-reversePreparingTime}s`;
@@ -61,6 +65,23 @@ function updateRoomStatus(room, nextRoundStart, state) {
     countdowns[room] = setInterval(updateStatus, 1000);
 }
 
+function updateRoomWaitingForPlayers(room, playersNeeded) {
+    if (countdowns[room]) {
+        clearInterval(countdowns[room]);
+    }
+
+    const statusElement = document.getElementById(`status-${room}`);
+    const countdownElement = document.getElementById(`countdown-${room}`);
+    const btnElement = document.getElementById(`btn-${room}`);
+
+    statusElement.innerHTML = `Waiting for ${playersNeeded} more player${playersNeeded > 1 ? 's' : ''}`;
+    countdownElement.textContent = '';
+    btnElement.disabled = false;
+    btnElement.textContent = "Join Room";
+
+    countdowns[room] = setTimeout(() => checkRoomStatus(room), 3000);
+}
+
 function updateRoomStatusError(room) {
     const statusElement = document.getElementById(`status-${room}`);
     const countdownElement = document.getElementById(`countdown-${room}`);
@@ -79,7 +100,8 @@ function formatTime(seconds) {
 }
 
 function initializeRooms() {
-    ['A', 'B'].forEach(room => {
+    document.querySelectorAll('.room[data-room-id]').forEach(element => {
+        const room = element.dataset.roomId;
         const btnElement = document.getElementById(`btn-${room}`);
         if (!btnElement) {
             console.error(`Button element for room ${room} not found`);
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 /*
 GameMsgType category of msg pipeline control
 */
@@ -9,9 +11,40 @@ const (
 	PlayerPositionType GameMsgType = "playerPosition"
 	ItemActionType     GameMsgType = "itemAction"
 	ItemCollectedType  GameMsgType = "itemCollected"
+	ItemExpiredType    GameMsgType = "itemExpired"
+	HazardHitType      GameMsgType = "hazardTriggered"
 	PlayerChatMsgType  GameMsgType = "playerChatMsg"
 	ErrorType          GameMsgType = "errorMsg"
 	AlertType          GameMsgType = "alertMsg"
+	RoomInfoType       GameMsgType = "roomInfo"
+	StateUpdateType    GameMsgType = "stateUpdate"
+	RoundResultType    GameMsgType = "roundResult"
+	PlayerGateType     GameMsgType = "waitingForPlayers"
+	PlayerReadyType    GameMsgType = "playerReady"
+	ReadyStateType     GameMsgType = "readyState"
+	EffectAppliedType  GameMsgType = "effectApplied"
+	EffectExpiredType  GameMsgType = "effectExpired"
+	AttackType         GameMsgType = "attack"
+	ObstacleHitType    GameMsgType = "obstacleDamaged"
+	ObstacleBrokeType  GameMsgType = "obstacleRemoved"
+	EntityPositionType GameMsgType = "entityPosition"
+	EntityHitType      GameMsgType = "entityHit"
+	EnterViewType      GameMsgType = "enterView"
+	LeaveViewType      GameMsgType = "leaveView"
+	ChooseTeamType     GameMsgType = "chooseTeam"
+	TeamStateType      GameMsgType = "teamState"
+
+	// bomberman
+	BombPlacedType       GameMsgType = "bombPlaced"
+	BombExplodedType     GameMsgType = "bombExploded"
+	PlayerEliminatedType GameMsgType = "playerEliminated"
+
+	// capture the flag
+	BaseType      GameMsgType = "base"
+	FlagStateType GameMsgType = "flagState"
+	// king of the hill
+	ZonePositionType GameMsgType = "zonePosition"
+	ZoneControlType  GameMsgType = "zoneControl"
 )
 
 /*
@@ -22,6 +55,11 @@ type Position struct {
 	Y int `json:"y"`
 }
 
+// Cell returns the position, it is promoted to every type embedding one.
+func (p *Position) Cell() *Position {
+	return p
+}
+
 type PlayerPosition struct {
 	Valid     bool   `json:"valid"`
 	ID        string `json:"id"`
@@ -29,6 +67,27 @@ type PlayerPosition struct {
 	*Position `json:"position"`
 }
 
+/*
+Obstacle category of destructible obstacle control
+*/
+type Obstacle struct {
+	*Position        // flattened, clients read obstacles as plain positions
+	Type      string `json:"type"`
+	HP        int    `json:"hp"`
+	MaxHP     int    `json:"maxHp"` // 0 can't be broken
+}
+
+// Attack targets the cell at Position, next to the attacking player.
+type Attack struct {
+	ID        string `json:"id"`
+	*Position `json:"position"`
+}
+
+type ObstacleHit struct {
+	By        string `json:"by"`
+	*Obstacle `json:"obstacle"`
+}
+
 type StartPosition struct {
 	Site      []map[string]int `json:"site"`
 	UserCount int
@@ -45,18 +104,164 @@ type Item struct {
 type ItemAction struct {
 	Valid     bool   `json:"valid"`
 	ID        string `json:"id"`
+	ExpiresAt int64  `json:"expiresAt,omitempty"` // unix milliseconds, 0 never expires
 	*Item     `json:"item"`
 	*Position `json:"position"`
 }
 
 type Action struct {
-	ID     string `json:"id"`
-	Effect string `json:"effect"`
+	ID        string `json:"id"`
+	Effect    string `json:"effect"`
+	Source    string `json:"source,omitempty"`    // player whose item caused the effect
+	ExpiresAt int64  `json:"expiresAt,omitempty"` // unix milliseconds
 }
 
 type ScoreUpdate struct {
-	ID    string `json:"id"`
-	Score int    `json:"score"`
+	ID        string `json:"id"`
+	Score     int    `json:"score"`
+	Team      string `json:"team,omitempty"`
+	TeamScore int    `json:"teamScore,omitempty"`
+}
+
+/*
+Team category of team room control
+*/
+type TeamChoice struct {
+	ID   string `json:"id"`
+	Team string `json:"team"`
+}
+
+type TeamState struct {
+	Assignment string              `json:"assignment"` // auto or lobby
+	Teams      map[string][]string `json:"teams"`      // members by team, the picks until a round assigns them
+}
+
+/*
+PlayerGate category of round start control
+*/
+type PlayerGate struct {
+	Needed    int   `json:"needed"`
+	Connected int   `json:"connected"`
+	Required  int   `json:"required"`
+	StartAt   int64 `json:"startAt,omitempty"` // unix milliseconds, set once the start countdown runs
+}
+
+type ReadyState struct {
+	Ready    []string `json:"ready"`
+	Players  []string `json:"players"`
+	Deadline int64    `json:"deadline"` // unix milliseconds, unready players spectate after it
+}
+
+/*
+RoundResult category of round end control
+*/
+type PlayerResult struct {
+	ID               string         `json:"id"`
+	Rank             int            `json:"rank"`
+	Score            int            `json:"score"`
+	ItemsCollected   map[string]int `json:"itemsCollected"`
+	Moves            int            `json:"moves"`
+	InvalidMoves     int            `json:"invalidMoves"`
+	ConnectedSeconds float64        `json:"connectedSeconds"`
+	Team             string         `json:"team,omitempty"`
+}
+
+type TeamResult struct {
+	Team    string   `json:"team"`
+	Rank    int      `json:"rank"`
+	Score   int      `json:"score"`
+	Members []string `json:"members"`
+}
+
+type RoundResult struct {
+	HubID    string          `json:"hubId"`
+	Mode     string          `json:"mode"`
+	Reason   string          `json:"reason"`
+	EndedAt  time.Time       `json:"endedAt"`
+	Winners  []string        `json:"winners"`
+	Rankings []*PlayerResult `json:"rankings"`
+	Teams    []*TeamResult   `json:"teams,omitempty"`
+}
+
+/*
+Bomb category of bomberman control
+*/
+type Bomb struct {
+	ID        string `json:"id"`
+	Radius    int    `json:"radius"`
+	ExplodeAt int64  `json:"explodeAt"` // unix milliseconds, server time
+	*Position `json:"position"`
+}
+
+type Explosion struct {
+	ID        string      `json:"id"`
+	Cells     []*Position `json:"cells"`
+	Destroyed []*Position `json:"destroyed"`
+	Hit       []string    `json:"hit"`
+	*Position `json:"position"`
+}
+
+type Elimination struct {
+	ID string `json:"id"`
+	By string `json:"by"`
+}
+
+/*
+Flag category of capture the flag control
+*/
+type Base struct {
+	Team  string      `json:"team"`
+	Cells []*Position `json:"cells"`
+}
+
+type Flag struct {
+	Team      string `json:"team"`
+	Event     string `json:"event"`              // placed, taken, carried, dropped, returned or captured
+	Carrier   string `json:"carrier,omitempty"`  // player holding the flag
+	By        string `json:"by,omitempty"`       // player who caused the event
+	ReturnAt  int64  `json:"returnAt,omitempty"` // unix milliseconds, a dropped flag goes home then
+	*Position `json:"position"`
+}
+
+/*
+Zone category of king of the hill control
+*/
+type Zone struct {
+	Cells  []*Position `json:"cells"`
+	MoveAt int64       `json:"moveAt,omitempty"` // unix milliseconds, the zone moves then
+}
+
+type ZoneControl struct {
+	Holders   []string `json:"holders"`        // players standing in the zone
+	Team      string   `json:"team,omitempty"` // team of the holders in team rooms
+	Contested bool     `json:"contested"`      // nobody scores while the zone is contested
+}
+
+/*
+Entity category of non-player control
+*/
+type Entity struct {
+	ID        string `json:"id"`
+	Kind      string `json:"kind"` // guard or monster
+	*Position `json:"position"`
+}
+
+// EntityHit reports an entity catching a player.
+type EntityHit struct {
+	ID         string `json:"id"`
+	By         string `json:"by"`
+	Points     int    `json:"points,omitempty"` // taken from the player
+	KnockedOut bool   `json:"knockedOut"`       // sent back to a start position
+}
+
+/*
+ViewChange category of fog of war control
+*/
+type ViewChange struct {
+	Kind      string   `json:"kind"` // player, entity or item
+	ID        string   `json:"id"`
+	State     *GameMsg `json:"state,omitempty"` // draws what came into view
+	*Position `json:"position"`
 }
 
 /*
@@ -75,6 +280,18 @@ type Alert struct {
 	Text string `json:"text"`
 }
 
+type RoomInfo struct {
+	ID     string `json:"id"`
+	Mode   string `json:"mode"`
+	Map    string `json:"map,omitempty"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	// fog of war, players only see what is this close (0 no limit) and, with
+	// LineOfSight, not hidden behind obstacles
+	VisibilityRadius int  `json:"visibilityRadius,omitempty"`
+	LineOfSight      bool `json:"lineOfSight,omitempty"`
+}
+
 type Error struct {
 	ID    string `json:"id"`
 	Error string `json:"error"`
@@ -84,3 +301,9 @@ type GameMsg struct {
 	Type    GameMsgType `json:"type"`
 	Content interface{} `json:"content"`
 }
+
+// StateUpdate batches the events of one simulation tick.
+type StateUpdate struct {
+	Tick   uint64     `json:"tick"`
+	Events []*GameMsg `json:"events"`
+}